@@ -5,6 +5,7 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"time"
 )
 
 const (
@@ -12,10 +13,21 @@ const (
 	pathUserGet        = "/cgi-bin/user/get"
 	pathUserUpdate     = "/cgi-bin/user/update"
 	pathUserDelete     = "/cgi-bin/user/delete"
+	pathUserBatchDel   = "/cgi-bin/user/batchdelete"
+	pathJoinQrcode     = "/cgi-bin/corp/get_join_qrcode"
+	pathActiveStat     = "/cgi-bin/user/get_active_stat"
 	pathUserSimpleList = "/cgi-bin/user/simplelist"
 	pathUserList       = "/cgi-bin/user/list"
+	pathUserListID     = "/cgi-bin/user/list_id"
 	pathUserInvite     = "/cgi-bin/batch/invite"
+	pathUserIDByMobile = "/cgi-bin/user/getuserid"
+	pathUserIDByEmail  = "/cgi-bin/user/get_userid_by_email"
+	pathToOpenID       = "/cgi-bin/user/convert_to_openid"
+	pathToUserID       = "/cgi-bin/user/convert_to_userid"
 	pathDepartmentList = "/cgi-bin/department/list"
+	pathDeptCreate     = "/cgi-bin/department/create"
+	pathDeptUpdate     = "/cgi-bin/department/update"
+	pathDeptDelete     = "/cgi-bin/department/delete"
 )
 
 type addressService service
@@ -27,7 +39,8 @@ func (b *addressService) WithContext(ctx context.Context) *addressService {
 	}
 }
 
-// https://work.weixin.qq.com/api/doc/90000/90135/90195
+// 成员，字段含义参考：https://developer.work.weixin.qq.com/document/path/90196
+// 标记为只读的字段仅在读取成员时返回，创建、更新成员时无需填写
 type User struct {
 	baseResponse
 	Userid           string           `json:"userid"`
@@ -39,19 +52,41 @@ type User struct {
 	Position         string           `json:"position,omitempty"`
 	Gender           string           `json:"gender,omitempty"`
 	Email            string           `json:"email,omitempty"`
+	BizMail          string           `json:"biz_mail,omitempty"`
 	IsLeaderInDept   []int            `json:"is_leader_in_dept,omitempty"`
+	DirectLeader     []string         `json:"direct_leader,omitempty"`
 	Enable           *int             `json:"enable,omitempty"`
 	AvatarMediaid    string           `json:"avatar_mediaid,omitempty"`
+	Avatar           string           `json:"avatar,omitempty"`       // 只读，头像 URL
+	ThumbAvatar      string           `json:"thumb_avatar,omitempty"` // 只读，头像缩略图 URL
 	Telephone        string           `json:"telephone,omitempty"`
 	Address          string           `json:"address,omitempty"`
+	OpenUserid       string           `json:"open_userid,omitempty"` // 只读，全局唯一的 userid
 	MainDepartment   int              `json:"main_department,omitempty"`
 	Extattr          *Extattr         `json:"extattr,omitempty"`
+	Status           int              `json:"status,omitempty"`  // 只读，激活状态：1 已激活，2 已禁用，4 未激活，5 退出企业
+	QrCode           string           `json:"qr_code,omitempty"` // 只读，员工个人二维码
 	ToInvite         *bool            `json:"to_invite,omitempty"`
+	EnglishName      string           `json:"english_name,omitempty"` // 已废弃，企业微信已改用 alias
 	ExternalPosition string           `json:"external_position,omitempty"`
 	ExternalProfile  *ExternalProfile `json:"external_profile,omitempty"`
-	DirectLeader     []string         `json:"direct_leader,omitempty"`
 }
 
+// 成员激活状态
+const (
+	UserStatusActive   = 1 // 已激活
+	UserStatusDisabled = 2 // 已禁用
+	UserStatusInactive = 4 // 未激活
+	UserStatusQuit     = 5 // 退出企业
+)
+
+// 扩展属性类型
+const (
+	AttrTypeText        = 0 // 文本
+	AttrTypeWeb         = 1 // 网页
+	AttrTypeMiniprogram = 2 // 小程序，仅对外属性支持
+)
+
 type Text struct {
 	Value string `json:"value"`
 }
@@ -62,10 +97,11 @@ type Web struct {
 }
 
 type Attrs struct {
-	Type int    `json:"type,omitempty"`
-	Name string `json:"name,omitempty"`
-	Text Text   `json:"text,omitempty"`
-	Web  Web    `json:"web,omitempty"`
+	Type        int          `json:"type"`
+	Name        string       `json:"name,omitempty"`
+	Text        *Text        `json:"text,omitempty"`
+	Web         *Web         `json:"web,omitempty"`
+	Miniprogram *Miniprogram `json:"miniprogram,omitempty"`
 }
 
 type Extattr struct {
@@ -79,11 +115,11 @@ type Miniprogram struct {
 }
 
 type ExternalAttr struct {
-	Type        int         `json:"type,omitempty"`
-	Name        string      `json:"name,omitempty"`
-	Text        Text        `json:"text,omitempty"`
-	Web         Web         `json:"web,omitempty"`
-	Miniprogram Miniprogram `json:"miniprogram,omitempty"`
+	Type        int          `json:"type"`
+	Name        string       `json:"name,omitempty"`
+	Text        *Text        `json:"text,omitempty"`
+	Web         *Web         `json:"web,omitempty"`
+	Miniprogram *Miniprogram `json:"miniprogram,omitempty"`
 }
 
 type ExternalProfile struct {
@@ -199,6 +235,7 @@ type DetailUserList struct {
 // 参考链接：https://developer.work.weixin.qq.com/document/path/90201
 // departmentID 部门ID，根部门填 1
 // recursive 是否递归获取子部门成员，0 表示不需要递归获取，否则表示需要递归获取
+// 注意：新创建的应用已无法调用该接口，可使用 MemberIterator 代替
 func (b *addressService) ListMemberDetail(departmentID, recursive int) (result *DetailUserList, err error) {
 	failCount := -1
 	if departmentID < 1 {
@@ -246,6 +283,7 @@ type SimpleUserWithMainDepartment struct {
 // 参考链接：https://developer.work.weixin.qq.com/document/path/90201
 // departmentID 部门ID，根部门填 1
 // recursive 是否递归获取子部门成员，0 表示不需要递归获取，否则表示需要递归获取
+// 注意：新创建的应用已无法调用该接口，可使用 MemberIterator 代替
 func (b *addressService) ListMembers(departmentID, recursive int) (result *UserList, err error) {
 	failCount := -1
 	if departmentID < 1 {
@@ -276,6 +314,54 @@ func (b *addressService) ListMembers(departmentID, recursive int) (result *UserL
 	return nil, err
 }
 
+type listMemberID struct {
+	Cursor string `json:"cursor,omitempty"`
+	Limit  int    `json:"limit,omitempty"`
+}
+
+// 成员 ID 列表，同一成员属于多个部门时会出现多次
+type MemberIDList struct {
+	baseResponse
+	NextCursor string     `json:"next_cursor"`
+	DeptUser   []DeptUser `json:"dept_user"`
+}
+
+type DeptUser struct {
+	Userid     string `json:"userid"`
+	Department int    `json:"department"`
+}
+
+// ListMemberID 通讯录：获取成员 ID 列表
+// 参考链接：https://developer.work.weixin.qq.com/document/path/96067
+// cursor 分页游标，首次请求填空字符串，之后填上一次返回的 next_cursor
+// limit 分页大小，取值范围 1 ~ 10000，填 0 时使用企业微信的默认值
+func (b *addressService) ListMemberID(cursor string, limit int) (result *MemberIDList, err error) {
+	failCount := -1
+	if limit < 0 || limit > 10000 {
+		return nil, fmt.Errorf("invalid limit: %d", limit)
+	}
+
+	// 默认尝试一次，即不进行失败重试
+	for failCount < b.client.maxRetryTimes {
+		failCount++
+		body := listMemberID{Cursor: cursor, Limit: limit}
+		var req *http.Request
+		req, err = b.client.newRequest(http.MethodPost, pathUserListID, body)
+		if err != nil {
+			continue // 如果循环结束，则会返回该 err
+		}
+		result = new(MemberIDList)
+		err = (*service)(b).doRequest(req, result)
+		if err != nil {
+			continue // 如果循环结束，则会返回该 err
+		}
+		// 成功，return
+		return result, nil
+	}
+	// 失败，返回最后一次请求的 err
+	return nil, err
+}
+
 // 通讯录：更新成员
 // 参考链接：https://work.weixin.qq.com/api/doc/90000/90135/90197
 func (b *addressService) UpdateMember(user *User) (result *UserResp, err error) {
@@ -324,6 +410,151 @@ func (b *addressService) DeleteMember(userID string) (result *UserResp, err erro
 	return nil, err
 }
 
+// 批量删除成员每次最多 200 个
+const batchDeleteLimit = 200
+
+type batchDelete struct {
+	UseridList []string `json:"useridlist"`
+}
+
+type BatchDeleteResp struct {
+	baseResponse
+	// 已成功删除的成员数
+	Deleted int `json:"-"`
+}
+
+// 通讯录：批量删除成员
+// 参考链接：https://developer.work.weixin.qq.com/document/path/90199
+// 超过 200 个时自动分批删除，某一批失败时停止，已删除的成员数通过 result.Deleted 返回
+// 请求出错时 result 也不为 nil，调用方可以通过 result.Deleted 得知之前的批次已删除的成员数
+func (b *addressService) BatchDeleteMember(userIDs []string) (result *BatchDeleteResp, err error) {
+	deleted := 0
+	for len(userIDs) > 0 {
+		n := len(userIDs)
+		if n > batchDeleteLimit {
+			n = batchDeleteLimit
+		}
+		result, err = b.batchDeleteMember(userIDs[:n])
+		if err != nil {
+			return &BatchDeleteResp{Deleted: deleted}, err
+		}
+		if result.ErrCode != 0 {
+			break
+		}
+		deleted += n
+		userIDs = userIDs[n:]
+	}
+	if result == nil {
+		result = new(BatchDeleteResp)
+	}
+	result.Deleted = deleted
+	return result, nil
+}
+
+func (b *addressService) batchDeleteMember(userIDs []string) (result *BatchDeleteResp, err error) {
+	failCount := -1
+	// 默认尝试一次，即不进行失败重试
+	for failCount < b.client.maxRetryTimes {
+		failCount++
+		body := batchDelete{UseridList: userIDs}
+		var req *http.Request
+		req, err = b.client.newRequest(http.MethodPost, pathUserBatchDel, body)
+		if err != nil {
+			continue // 如果循环结束，则会返回该 err
+		}
+		result = new(BatchDeleteResp)
+		err = (*service)(b).doRequest(req, result)
+		if err != nil {
+			continue // 如果循环结束，则会返回该 err
+		}
+		// 成功，return
+		return result, nil
+	}
+	// 失败，返回最后一次请求的 err
+	return nil, err
+}
+
+// 加入企业二维码尺寸
+const (
+	QrcodeSize171  = 1 // 171 x 171
+	QrcodeSize399  = 2 // 399 x 399
+	QrcodeSize741  = 3 // 741 x 741
+	QrcodeSize2052 = 4 // 2052 x 2052
+)
+
+type JoinQrcodeResp struct {
+	baseResponse
+	JoinQrcode string `json:"join_qrcode"`
+}
+
+// 通讯录：获取加入企业二维码，返回二维码图片链接，有效期 7 天
+// 参考链接：https://developer.work.weixin.qq.com/document/path/91714
+// sizeType 二维码尺寸，取值 QrcodeSize171 ~ QrcodeSize2052，填 0 时默认为 QrcodeSize399
+func (b *addressService) GetJoinQrcode(sizeType int) (result *JoinQrcodeResp, err error) {
+	failCount := -1
+	if sizeType < 0 || sizeType > QrcodeSize2052 {
+		return nil, fmt.Errorf("invalid size type: %d", sizeType)
+	}
+	var qs []string
+	if sizeType != 0 {
+		qs = append(qs, fmt.Sprintf("size_type=%d", sizeType))
+	}
+
+	// 默认尝试一次，即不进行失败重试
+	for failCount < b.client.maxRetryTimes {
+		failCount++
+		var req *http.Request
+		req, err = b.client.newRequest(http.MethodGet, pathJoinQrcode, nil, qs...)
+		if err != nil {
+			continue // 如果循环结束，则会返回该 err
+		}
+		result = new(JoinQrcodeResp)
+		err = (*service)(b).doRequest(req, result)
+		if err != nil {
+			continue // 如果循环结束，则会返回该 err
+		}
+		// 成功，return
+		return result, nil
+	}
+	// 失败，返回最后一次请求的 err
+	return nil, err
+}
+
+type activeStat struct {
+	Date string `json:"date"`
+}
+
+type ActiveStatResp struct {
+	baseResponse
+	ActiveCnt int `json:"active_cnt"`
+}
+
+// 通讯录：获取企业活跃成员数
+// 参考链接：https://developer.work.weixin.qq.com/document/path/92714
+// date 只能查询最近 30 天内的日期
+func (b *addressService) GetActiveStat(date time.Time) (result *ActiveStatResp, err error) {
+	failCount := -1
+	// 默认尝试一次，即不进行失败重试
+	for failCount < b.client.maxRetryTimes {
+		failCount++
+		body := activeStat{Date: date.Format("2006-01-02")}
+		var req *http.Request
+		req, err = b.client.newRequest(http.MethodPost, pathActiveStat, body)
+		if err != nil {
+			continue // 如果循环结束，则会返回该 err
+		}
+		result = new(ActiveStatResp)
+		err = (*service)(b).doRequest(req, result)
+		if err != nil {
+			continue // 如果循环结束，则会返回该 err
+		}
+		// 成功，return
+		return result, nil
+	}
+	// 失败，返回最后一次请求的 err
+	return nil, err
+}
+
 type invite struct {
 	User []string `json:"user"`
 }
@@ -353,6 +584,131 @@ func (b *addressService) InviteMember(userID []string) (result *UserResp, err er
 	return nil, err
 }
 
+// 邮箱类型
+const (
+	EmailTypeCorp     = 1 // 企业邮箱
+	EmailTypePersonal = 2 // 个人邮箱
+)
+
+type userIDQuery struct {
+	Mobile    string `json:"mobile,omitempty"`
+	Email     string `json:"email,omitempty"`
+	EmailType int    `json:"email_type,omitempty"`
+	Userid    string `json:"userid,omitempty"`
+	Openid    string `json:"openid,omitempty"`
+}
+
+type UserIDResp struct {
+	baseResponse
+	Userid string `json:"userid"`
+}
+
+type OpenIDResp struct {
+	baseResponse
+	Openid string `json:"openid"`
+}
+
+// 通讯录：手机号获取 userid
+// 参考链接：https://developer.work.weixin.qq.com/document/path/95402
+func (b *addressService) GetUserIDByMobile(mobile string) (result *UserIDResp, err error) {
+	failCount := -1
+	// 默认尝试一次，即不进行失败重试
+	for failCount < b.client.maxRetryTimes {
+		failCount++
+		body := userIDQuery{Mobile: mobile}
+		var req *http.Request
+		req, err = b.client.newRequest(http.MethodPost, pathUserIDByMobile, body)
+		if err != nil {
+			continue // 如果循环结束，则会返回该 err
+		}
+		result = new(UserIDResp)
+		err = (*service)(b).doRequest(req, result)
+		if err != nil {
+			continue // 如果循环结束，则会返回该 err
+		}
+		// 成功，return
+		return result, nil
+	}
+	// 失败，返回最后一次请求的 err
+	return nil, err
+}
+
+// 通讯录：邮箱获取 userid
+// 参考链接：https://developer.work.weixin.qq.com/document/path/95895
+// emailType 邮箱类型，EmailTypeCorp 或 EmailTypePersonal，填 0 时默认为企业邮箱
+func (b *addressService) GetUserIDByEmail(email string, emailType int) (result *UserIDResp, err error) {
+	failCount := -1
+	// 默认尝试一次，即不进行失败重试
+	for failCount < b.client.maxRetryTimes {
+		failCount++
+		body := userIDQuery{Email: email, EmailType: emailType}
+		var req *http.Request
+		req, err = b.client.newRequest(http.MethodPost, pathUserIDByEmail, body)
+		if err != nil {
+			continue // 如果循环结束，则会返回该 err
+		}
+		result = new(UserIDResp)
+		err = (*service)(b).doRequest(req, result)
+		if err != nil {
+			continue // 如果循环结束，则会返回该 err
+		}
+		// 成功，return
+		return result, nil
+	}
+	// 失败，返回最后一次请求的 err
+	return nil, err
+}
+
+// 通讯录：userid 转 openid
+// 参考链接：https://developer.work.weixin.qq.com/document/path/90202
+func (b *addressService) ConvertToOpenID(userID string) (result *OpenIDResp, err error) {
+	failCount := -1
+	// 默认尝试一次，即不进行失败重试
+	for failCount < b.client.maxRetryTimes {
+		failCount++
+		body := userIDQuery{Userid: userID}
+		var req *http.Request
+		req, err = b.client.newRequest(http.MethodPost, pathToOpenID, body)
+		if err != nil {
+			continue // 如果循环结束，则会返回该 err
+		}
+		result = new(OpenIDResp)
+		err = (*service)(b).doRequest(req, result)
+		if err != nil {
+			continue // 如果循环结束，则会返回该 err
+		}
+		// 成功，return
+		return result, nil
+	}
+	// 失败，返回最后一次请求的 err
+	return nil, err
+}
+
+// 通讯录：openid 转 userid
+// 参考链接：https://developer.work.weixin.qq.com/document/path/90202
+func (b *addressService) ConvertToUserID(openID string) (result *UserIDResp, err error) {
+	failCount := -1
+	// 默认尝试一次，即不进行失败重试
+	for failCount < b.client.maxRetryTimes {
+		failCount++
+		body := userIDQuery{Openid: openID}
+		var req *http.Request
+		req, err = b.client.newRequest(http.MethodPost, pathToUserID, body)
+		if err != nil {
+			continue // 如果循环结束，则会返回该 err
+		}
+		result = new(UserIDResp)
+		err = (*service)(b).doRequest(req, result)
+		if err != nil {
+			continue // 如果循环结束，则会返回该 err
+		}
+		// 成功，return
+		return result, nil
+	}
+	// 失败，返回最后一次请求的 err
+	return nil, err
+}
+
 // 部门列表
 type DepartmentList struct {
 	baseResponse
@@ -360,10 +716,15 @@ type DepartmentList struct {
 }
 
 type Department struct {
-	ID       int    `json:"id"`
-	Name     string `json:"name"`
-	Parentid int    `json:"parentid"`
-	Order    int    `json:"order"`
+	ID       int    `json:"id,omitempty"`
+	Name     string `json:"name,omitempty"`
+	Parentid int    `json:"parentid,omitempty"`
+	Order    int    `json:"order,omitempty"`
+}
+
+type DepartmentResp struct {
+	baseResponse
+	ID int `json:"id,omitempty"`
 }
 
 // 通讯录：获取部门列表
@@ -389,3 +750,76 @@ func (b *addressService) DepartmentList(departmentID int) (result *DepartmentLis
 	// 失败，返回最后一次请求的 err
 	return nil, err
 }
+
+// 通讯录：创建部门
+// 参考链接：https://developer.work.weixin.qq.com/document/path/90205
+// department.ID 不填时由企业微信自动生成，通过 result.ID 返回
+func (b *addressService) CreateDepartment(department *Department) (result *DepartmentResp, err error) {
+	failCount := -1
+	// 默认尝试一次，即不进行失败重试
+	for failCount < b.client.maxRetryTimes {
+		failCount++
+		var req *http.Request
+		req, err = b.client.newRequest(http.MethodPost, pathDeptCreate, department)
+		if err != nil {
+			continue // 如果循环结束，则会返回该 err
+		}
+		result = new(DepartmentResp)
+		err = (*service)(b).doRequest(req, result)
+		if err != nil {
+			continue // 如果循环结束，则会返回该 err
+		}
+		// 成功，return
+		return result, nil
+	}
+	// 失败，返回最后一次请求的 err
+	return nil, err
+}
+
+// 通讯录：更新部门
+// 参考链接：https://developer.work.weixin.qq.com/document/path/90206
+func (b *addressService) UpdateDepartment(department *Department) (result *DepartmentResp, err error) {
+	failCount := -1
+	// 默认尝试一次，即不进行失败重试
+	for failCount < b.client.maxRetryTimes {
+		failCount++
+		var req *http.Request
+		req, err = b.client.newRequest(http.MethodPost, pathDeptUpdate, department)
+		if err != nil {
+			continue // 如果循环结束，则会返回该 err
+		}
+		result = new(DepartmentResp)
+		err = (*service)(b).doRequest(req, result)
+		if err != nil {
+			continue // 如果循环结束，则会返回该 err
+		}
+		// 成功，return
+		return result, nil
+	}
+	// 失败，返回最后一次请求的 err
+	return nil, err
+}
+
+// 通讯录：删除部门，部门下不能有成员和子部门
+// 参考链接：https://developer.work.weixin.qq.com/document/path/90207
+func (b *addressService) DeleteDepartment(departmentID int) (result *DepartmentResp, err error) {
+	failCount := -1
+	// 默认尝试一次，即不进行失败重试
+	for failCount < b.client.maxRetryTimes {
+		failCount++
+		var req *http.Request
+		req, err = b.client.newRequest(http.MethodGet, pathDeptDelete, nil, fmt.Sprintf("id=%d", departmentID))
+		if err != nil {
+			continue // 如果循环结束，则会返回该 err
+		}
+		result = new(DepartmentResp)
+		err = (*service)(b).doRequest(req, result)
+		if err != nil {
+			continue // 如果循环结束，则会返回该 err
+		}
+		// 成功，return
+		return result, nil
+	}
+	// 失败，返回最后一次请求的 err
+	return nil, err
+}
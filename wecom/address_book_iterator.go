@@ -0,0 +1,96 @@
+// address_book_iterator.go 基于 user/list_id 的游标分页，遍历企业的全部成员
+// 参考链接：https://developer.work.weixin.qq.com/document/path/96067
+package wecom
+
+import (
+	"context"
+)
+
+// MemberIterator 成员迭代器，自动跟随 next_cursor 翻页
+//
+//	it := client.Address.WithContext(ctx).MemberIterator(0)
+//	for it.Next() {
+//		fmt.Println(it.Member().Userid)
+//	}
+//	if err := it.Err(); err != nil {
+//		// ...
+//	}
+type MemberIterator struct {
+	service *addressService
+	limit   int
+
+	cursor string
+	buf    []DeptUser
+	cur    DeptUser
+	// 已拉取最后一页
+	done bool
+	err  error
+}
+
+// MemberIterator 创建成员迭代器，limit 为每页大小，填 0 时使用企业微信的默认值
+// 迭代器使用 WithContext 设置的 Context，Context 被取消后迭代终止
+func (b *addressService) MemberIterator(limit int) *MemberIterator {
+	return &MemberIterator{
+		service: b,
+		limit:   limit,
+	}
+}
+
+// Next 移动到下一个成员，没有更多成员或发生错误时返回 false
+func (it *MemberIterator) Next() bool {
+	for len(it.buf) == 0 {
+		if it.done || it.err != nil {
+			return false
+		}
+		it.fetch()
+	}
+	it.cur = it.buf[0]
+	it.buf = it.buf[1:]
+	return true
+}
+
+// Member 返回当前成员，仅在 Next 返回 true 后有效
+func (it *MemberIterator) Member() DeptUser {
+	return it.cur
+}
+
+// Err 返回迭代过程中发生的错误
+func (it *MemberIterator) Err() error {
+	return it.err
+}
+
+// fetch 拉取下一页，触发频率限制时按指数退避重试
+func (it *MemberIterator) fetch() {
+	it.err = retryOnFreqLimit(it.service.ctx, func() error {
+		result, err := it.service.ListMemberID(it.cursor, it.limit)
+		if err == nil {
+			err = checkResponse(result)
+		}
+		if err != nil {
+			return err
+		}
+		it.buf = result.DeptUser
+		it.cursor = result.NextCursor
+		it.done = result.NextCursor == ""
+		return nil
+	})
+}
+
+// AllMemberIDs 通讯录：获取企业全部成员的 userid，已去重，顺序与接口返回一致
+func (b *addressService) AllMemberIDs(ctx context.Context) ([]string, error) {
+	it := b.WithContext(ctx).MemberIterator(0)
+	seen := make(map[string]struct{})
+	var ids []string
+	for it.Next() {
+		id := it.Member().Userid
+		if _, ok := seen[id]; ok {
+			continue
+		}
+		seen[id] = struct{}{}
+		ids = append(ids, id)
+	}
+	if err := it.Err(); err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
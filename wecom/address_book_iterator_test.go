@@ -0,0 +1,57 @@
+package wecom
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestMemberIterator(t *testing.T) {
+	defer func(d time.Duration) { freqLimitBackoff = d }(freqLimitBackoff)
+	freqLimitBackoff = time.Millisecond
+
+	pages := map[string]string{
+		"":   `{"errcode":0,"next_cursor":"c1","dept_user":[{"userid":"a","department":1},{"userid":"b","department":1}]}`,
+		"c1": `{"errcode":0,"next_cursor":"c2","dept_user":[]}`,
+		"c2": `{"errcode":0,"dept_user":[{"userid":"a","department":2},{"userid":"c","department":2}]}`,
+	}
+	limited := false
+	c, _ := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		var body listMemberID
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		if body.Cursor == "c2" && !limited {
+			limited = true
+			fmt.Fprint(w, `{"errcode":45009,"errmsg":"api freq out of limit"}`)
+			return
+		}
+		fmt.Fprint(w, pages[body.Cursor])
+	})
+
+	ids, err := c.Address.AllMemberIDs(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"a", "b", "c"}; !reflect.DeepEqual(ids, want) {
+		t.Fatalf("got %v, want %v", ids, want)
+	}
+	if !limited {
+		t.Fatal("freq limit not exercised")
+	}
+}
+
+func TestMemberIteratorError(t *testing.T) {
+	c, _ := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"errcode":48002,"errmsg":"api forbidden"}`)
+	})
+	it := c.Address.MemberIterator(0)
+	if it.Next() {
+		t.Fatal("Next returned true on error")
+	}
+	if e, ok := it.Err().(*Error); !ok || e.ErrCode != 48002 {
+		t.Fatalf("got err %v, want errcode 48002", it.Err())
+	}
+}
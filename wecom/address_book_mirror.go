@@ -0,0 +1,557 @@
+// address_book_mirror.go 本地通讯录镜像
+// 启动时通过列表接口全量加载，之后根据通讯录变更回调（change_contact）增量更新，并定期全量校准
+// 通讯录回调通知：https://developer.work.weixin.qq.com/document/path/90970
+package wecom
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 通讯录变更类型，与回调中的 ChangeType 一致
+type ChangeType string
+
+const (
+	ChangeCreateUser  ChangeType = "create_user"
+	ChangeUpdateUser  ChangeType = "update_user"
+	ChangeDeleteUser  ChangeType = "delete_user"
+	ChangeCreateParty ChangeType = "create_party"
+	ChangeUpdateParty ChangeType = "update_party"
+	ChangeDeleteParty ChangeType = "delete_party"
+	ChangeUpdateTag   ChangeType = "update_tag"
+)
+
+// ChangeContactEvent 通讯录变更事件，为解密后的回调 XML
+// 成员变更事件只包含发生变化的字段
+type ChangeContactEvent struct {
+	XMLName      xml.Name   `xml:"xml"`
+	ToUserName   string     `xml:"ToUserName"`
+	FromUserName string     `xml:"FromUserName"`
+	CreateTime   int64      `xml:"CreateTime"`
+	MsgType      string     `xml:"MsgType"`
+	Event        string     `xml:"Event"`
+	ChangeType   ChangeType `xml:"ChangeType"`
+
+	// 成员变更
+	UserID         string `xml:"UserID"`
+	NewUserID      string `xml:"NewUserID"`
+	Name           string `xml:"Name"`
+	Department     string `xml:"Department"`     // 逗号分隔的部门 ID
+	MainDepartment int    `xml:"MainDepartment"` // 主部门
+	IsLeaderInDept string `xml:"IsLeaderInDept"` // 逗号分隔，与 Department 一一对应
+	DirectLeader   string `xml:"DirectLeader"`   // 逗号分隔的直属上级
+	Position       string `xml:"Position"`
+	Mobile         string `xml:"Mobile"`
+	Gender         string `xml:"Gender"`
+	Email          string `xml:"Email"`
+	BizMail        string `xml:"BizMail"`
+	Status         int    `xml:"Status"`
+	Avatar         string `xml:"Avatar"`
+	Alias          string `xml:"Alias"`
+	Telephone      string `xml:"Telephone"`
+	Address        string `xml:"Address"`
+
+	// 部门变更，Name 与成员变更共用
+	ID       int `xml:"Id"`
+	ParentID int `xml:"ParentId"`
+	Order    int `xml:"Order"`
+
+	// 标签变更
+	TagID         int    `xml:"TagId"`
+	AddUserItems  string `xml:"AddUserItems"`
+	DelUserItems  string `xml:"DelUserItems"`
+	AddPartyItems string `xml:"AddPartyItems"`
+	DelPartyItems string `xml:"DelPartyItems"`
+}
+
+// ParseChangeContactEvent 解析解密后的回调消息
+func ParseChangeContactEvent(data []byte) (*ChangeContactEvent, error) {
+	ev := new(ChangeContactEvent)
+	if err := xml.Unmarshal(data, ev); err != nil {
+		return nil, err
+	}
+	if ev.Event != "change_contact" {
+		return nil, fmt.Errorf("not a change_contact event: %s", ev.Event)
+	}
+	return ev, nil
+}
+
+// DirectoryChange 镜像发生的一次变更，由 Subscribe 的回调接收
+type DirectoryChange struct {
+	Type ChangeType
+	// 成员变更时有效，删除时为删除前的数据
+	User *User
+	// 成员 userid 变更时，为变更前的 userid
+	OldUserID string
+	// 部门变更时有效，删除时为删除前的数据
+	Department *Department
+	// 标签变更时有效
+	TagID int
+	// 变更来源：callback 或 reconcile
+	Source string
+}
+
+// TagMembers 标签成员，仅根据回调事件维护
+type TagMembers struct {
+	Users   map[string]struct{}
+	Parties map[int]struct{}
+}
+
+// DirectoryMirror 本地通讯录镜像，可并发使用
+type DirectoryMirror struct {
+	service *addressService
+	rootID  int
+
+	mu sync.RWMutex
+	mirrorState
+	// Reconcile 拉取数据期间收到的事件，拉取完成后重新应用，避免被拉取前的数据覆盖
+	recording bool
+	replay    []*ChangeContactEvent
+	// 保证同一时刻只有一个 Reconcile
+	reconcileMu sync.Mutex
+
+	subMu       sync.Mutex
+	subID       int
+	subscribers map[int]func(DirectoryChange)
+}
+
+// NewDirectoryMirror 创建 rootDepartmentID 下的通讯录镜像，需要调用 Bootstrap 加载数据
+func (b *addressService) NewDirectoryMirror(rootDepartmentID int) *DirectoryMirror {
+	if rootDepartmentID == 0 {
+		rootDepartmentID = 1
+	}
+	return &DirectoryMirror{
+		service: &addressService{client: b.client},
+		rootID:  rootDepartmentID,
+		mirrorState: mirrorState{
+			users:       make(map[string]*User),
+			departments: make(map[int]*Department),
+			tags:        make(map[int]*TagMembers),
+		},
+		subscribers: make(map[int]func(DirectoryChange)),
+	}
+}
+
+// mirrorState 镜像的数据，由 DirectoryMirror.mu 保护
+type mirrorState struct {
+	users       map[string]*User
+	departments map[int]*Department
+	tags        map[int]*TagMembers
+}
+
+// Bootstrap 全量加载部门和成员，不会通知订阅者
+func (m *DirectoryMirror) Bootstrap(ctx context.Context) error {
+	dir, err := m.service.WithContext(ctx).CurrentDirectory(m.rootID)
+	if err != nil {
+		return err
+	}
+	users, departments := indexDirectory(dir)
+	m.mu.Lock()
+	m.users = users
+	m.departments = departments
+	m.mu.Unlock()
+	return nil
+}
+
+func indexDirectory(dir *Directory) (map[string]*User, map[int]*Department) {
+	users := make(map[string]*User, len(dir.Users))
+	for k := range dir.Users {
+		users[dir.Users[k].Userid] = &dir.Users[k]
+	}
+	departments := make(map[int]*Department, len(dir.Departments))
+	for k := range dir.Departments {
+		departments[dir.Departments[k].ID] = &dir.Departments[k]
+	}
+	return users, departments
+}
+
+// Subscribe 订阅镜像变更，返回取消订阅的函数
+// fn 在变更发生的 goroutine 中同步调用，不应执行耗时操作
+func (m *DirectoryMirror) Subscribe(fn func(DirectoryChange)) (cancel func()) {
+	m.subMu.Lock()
+	defer m.subMu.Unlock()
+	m.subID++
+	id := m.subID
+	m.subscribers[id] = fn
+	return func() {
+		m.subMu.Lock()
+		defer m.subMu.Unlock()
+		delete(m.subscribers, id)
+	}
+}
+
+func (m *DirectoryMirror) notify(changes []DirectoryChange) {
+	if len(changes) == 0 {
+		return
+	}
+	m.subMu.Lock()
+	subscribers := make([]func(DirectoryChange), 0, len(m.subscribers))
+	for _, fn := range m.subscribers {
+		subscribers = append(subscribers, fn)
+	}
+	m.subMu.Unlock()
+	for _, change := range changes {
+		for _, fn := range subscribers {
+			fn(change)
+		}
+	}
+}
+
+// GetMember 读取成员，镜像中不存在时调用 GetMember 接口，成员属于镜像范围内的部门时写入镜像
+// 返回值为深拷贝的副本，修改不会影响镜像
+func (m *DirectoryMirror) GetMember(userID string) (*User, error) {
+	m.mu.RLock()
+	user, ok := m.users[userID]
+	m.mu.RUnlock()
+	if ok {
+		return user.Clone(), nil
+	}
+
+	result, err := m.service.GetMember(userID)
+	if err != nil {
+		return nil, err
+	}
+	if err = checkResponse(result); err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	// 范围外的成员不写入镜像，否则 Reconcile 时会被误报为已删除
+	if m.inScope(result) {
+		m.users[userID] = result.Clone()
+	}
+	m.mu.Unlock()
+	return result, nil
+}
+
+// inScope 判断成员是否属于镜像范围内的部门，调用方需持有锁
+func (m *mirrorState) inScope(user *User) bool {
+	for _, id := range user.Department {
+		if _, ok := m.departments[id]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Members 返回镜像中的全部成员（深拷贝的副本）
+func (m *DirectoryMirror) Members() []User {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	users := make([]User, 0, len(m.users))
+	for _, user := range m.users {
+		users = append(users, *user.Clone())
+	}
+	return users
+}
+
+// Department 读取部门
+func (m *DirectoryMirror) Department(departmentID int) (Department, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	d, ok := m.departments[departmentID]
+	if !ok {
+		return Department{}, false
+	}
+	return *d, true
+}
+
+// OrgTree 根据镜像数据构建组织架构树
+func (m *DirectoryMirror) OrgTree() *OrgTree {
+	m.mu.RLock()
+	departments := make([]Department, 0, len(m.departments))
+	for _, d := range m.departments {
+		departments = append(departments, *d)
+	}
+	m.mu.RUnlock()
+	return NewOrgTree(departments, m.Members())
+}
+
+// TagMembers 返回标签成员（副本），标签成员只能通过回调事件获得
+func (m *DirectoryMirror) TagMembers(tagID int) (users []string, parties []int) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	tag, ok := m.tags[tagID]
+	if !ok {
+		return nil, nil
+	}
+	for id := range tag.Users {
+		users = append(users, id)
+	}
+	for id := range tag.Parties {
+		parties = append(parties, id)
+	}
+	return users, parties
+}
+
+// HandleEvent 应用一次通讯录变更回调
+// 镜像范围外的成员、部门会被忽略，移出范围的成员、部门视为被删除
+func (m *DirectoryMirror) HandleEvent(ev *ChangeContactEvent) {
+	m.mu.Lock()
+	change, ok := m.apply(ev, m.rootID)
+	if ok && m.recording {
+		m.replay = append(m.replay, ev)
+	}
+	m.mu.Unlock()
+
+	if ok {
+		m.notify([]DirectoryChange{change})
+	}
+}
+
+// apply 将事件应用到镜像数据，返回对应的变更，事件未引起变更时返回 false，调用方需持有锁
+func (m *mirrorState) apply(ev *ChangeContactEvent, rootID int) (DirectoryChange, bool) {
+	change := DirectoryChange{Type: ev.ChangeType, Source: "callback"}
+	switch ev.ChangeType {
+	case ChangeCreateUser, ChangeUpdateUser:
+		user := &User{Userid: ev.UserID}
+		old, existed := m.users[ev.UserID]
+		if existed {
+			user = old.Clone()
+		}
+		applyUserEvent(user, ev)
+		if ev.NewUserID != "" && ev.NewUserID != ev.UserID {
+			user.Userid = ev.NewUserID
+		}
+		if !m.inScope(user) {
+			if !existed {
+				return change, false
+			}
+			// 成员被移出镜像范围
+			delete(m.users, ev.UserID)
+			change.Type = ChangeDeleteUser
+			change.User = old
+			return change, true
+		}
+		if user.Userid != ev.UserID {
+			delete(m.users, ev.UserID)
+			change.OldUserID = ev.UserID
+		}
+		m.users[user.Userid] = user
+		change.User = user.Clone()
+	case ChangeDeleteUser:
+		user, ok := m.users[ev.UserID]
+		if !ok {
+			user = &User{Userid: ev.UserID}
+		}
+		delete(m.users, ev.UserID)
+		change.User = user
+	case ChangeCreateParty, ChangeUpdateParty:
+		d := &Department{ID: ev.ID}
+		old, existed := m.departments[ev.ID]
+		if existed {
+			c := *old
+			d = &c
+		}
+		if ev.Name != "" {
+			d.Name = ev.Name
+		}
+		if ev.ParentID != 0 {
+			d.Parentid = ev.ParentID
+		}
+		if ev.Order != 0 {
+			d.Order = ev.Order
+		}
+		if _, ok := m.departments[d.Parentid]; d.ID != rootID && (!ok || d.Parentid == d.ID) {
+			if !existed {
+				return change, false
+			}
+			// 部门被移出镜像范围，其下级部门及成员由下一次 Reconcile 修正
+			delete(m.departments, d.ID)
+			change.Type = ChangeDeleteParty
+			change.Department = old
+			return change, true
+		}
+		m.departments[d.ID] = d
+		c := *d
+		change.Department = &c
+	case ChangeDeleteParty:
+		d, ok := m.departments[ev.ID]
+		if !ok {
+			d = &Department{ID: ev.ID}
+		}
+		delete(m.departments, ev.ID)
+		change.Department = d
+	case ChangeUpdateTag:
+		tag, ok := m.tags[ev.TagID]
+		if !ok {
+			tag = &TagMembers{Users: make(map[string]struct{}), Parties: make(map[int]struct{})}
+			m.tags[ev.TagID] = tag
+		}
+		for _, id := range splitItems(ev.AddUserItems) {
+			tag.Users[id] = struct{}{}
+		}
+		for _, id := range splitItems(ev.DelUserItems) {
+			delete(tag.Users, id)
+		}
+		for _, id := range splitInts(ev.AddPartyItems) {
+			tag.Parties[id] = struct{}{}
+		}
+		for _, id := range splitInts(ev.DelPartyItems) {
+			delete(tag.Parties, id)
+		}
+		change.TagID = ev.TagID
+	default:
+		return change, false
+	}
+	return change, true
+}
+
+// applyUserEvent 将事件中非空的字段更新到 user
+func applyUserEvent(user *User, ev *ChangeContactEvent) {
+	if ev.Name != "" {
+		user.Name = ev.Name
+	}
+	if ev.Department != "" {
+		user.Department = splitInts(ev.Department)
+	}
+	if ev.MainDepartment != 0 {
+		user.MainDepartment = ev.MainDepartment
+	}
+	if ev.IsLeaderInDept != "" {
+		user.IsLeaderInDept = splitInts(ev.IsLeaderInDept)
+	}
+	if ev.DirectLeader != "" {
+		user.DirectLeader = splitItems(ev.DirectLeader)
+	}
+	if ev.Position != "" {
+		user.Position = ev.Position
+	}
+	if ev.Mobile != "" {
+		user.Mobile = ev.Mobile
+	}
+	if ev.Gender != "" {
+		user.Gender = ev.Gender
+	}
+	if ev.Email != "" {
+		user.Email = ev.Email
+	}
+	if ev.BizMail != "" {
+		user.BizMail = ev.BizMail
+	}
+	if ev.Status != 0 {
+		user.Status = ev.Status
+	}
+	if ev.Avatar != "" {
+		user.Avatar = ev.Avatar
+	}
+	if ev.Alias != "" {
+		user.Alias = ev.Alias
+	}
+	if ev.Telephone != "" {
+		user.Telephone = ev.Telephone
+	}
+	if ev.Address != "" {
+		user.Address = ev.Address
+	}
+}
+
+func splitItems(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func splitInts(s string) []int {
+	var ints []int
+	for _, item := range splitItems(s) {
+		if v, err := strconv.Atoi(item); err == nil {
+			ints = append(ints, v)
+		}
+	}
+	return ints
+}
+
+// Reconcile 全量拉取并与镜像比较，修正回调丢失等原因造成的偏差，并通知订阅者
+// 拉取期间收到的回调事件会在拉取完成后重新应用，通知中的成员、部门均为副本
+func (m *DirectoryMirror) Reconcile(ctx context.Context) error {
+	m.reconcileMu.Lock()
+	defer m.reconcileMu.Unlock()
+
+	m.mu.Lock()
+	m.recording = true
+	m.replay = nil
+	m.mu.Unlock()
+	defer func() {
+		m.mu.Lock()
+		m.recording = false
+		m.replay = nil
+		m.mu.Unlock()
+	}()
+
+	dir, err := m.service.WithContext(ctx).CurrentDirectory(m.rootID)
+	if err != nil {
+		return err
+	}
+	users, departments := indexDirectory(dir)
+
+	var changes []DirectoryChange
+	m.mu.Lock()
+	// 拉取的数据可能早于这些事件，重新应用以免丢失；标签不参与校准，使用临时的 tags
+	fetched := &mirrorState{users: users, departments: departments, tags: make(map[int]*TagMembers)}
+	for _, ev := range m.replay {
+		fetched.apply(ev, m.rootID)
+	}
+	for id, d := range departments {
+		old, ok := m.departments[id]
+		c := *d
+		if !ok {
+			changes = append(changes, DirectoryChange{Type: ChangeCreateParty, Department: &c, Source: "reconcile"})
+		} else if *old != *d {
+			changes = append(changes, DirectoryChange{Type: ChangeUpdateParty, Department: &c, Source: "reconcile"})
+		}
+	}
+	for id, d := range m.departments {
+		if _, ok := departments[id]; !ok {
+			changes = append(changes, DirectoryChange{Type: ChangeDeleteParty, Department: d, Source: "reconcile"})
+		}
+	}
+	for id, user := range users {
+		old, ok := m.users[id]
+		if !ok {
+			changes = append(changes, DirectoryChange{Type: ChangeCreateUser, User: user.Clone(), Source: "reconcile"})
+		} else if !reflect.DeepEqual(old, user) {
+			changes = append(changes, DirectoryChange{Type: ChangeUpdateUser, User: user.Clone(), Source: "reconcile"})
+		}
+	}
+	for id, user := range m.users {
+		if _, ok := users[id]; !ok {
+			changes = append(changes, DirectoryChange{Type: ChangeDeleteUser, User: user, Source: "reconcile"})
+		}
+	}
+	m.users = users
+	m.departments = departments
+	m.mu.Unlock()
+
+	m.notify(changes)
+	return nil
+}
+
+// Run 每隔 interval 执行一次 Reconcile，直到 ctx 被取消
+// onError 用于接收校准失败的错误，可以为 nil
+func (m *DirectoryMirror) Run(ctx context.Context, interval time.Duration, onError func(error)) error {
+	if interval <= 0 {
+		return fmt.Errorf("invalid reconcile interval: %s", interval)
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			if err := m.Reconcile(ctx); err != nil && onError != nil {
+				onError(err)
+			}
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package wecom
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func newMirrorTestClient(t *testing.T) *Client {
+	c, _ := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case pathDepartmentList:
+			fmt.Fprint(w, `{"errcode":0,"department":[{"id":1,"name":"root"},{"id":2,"name":"dev","parentid":1}]}`)
+		case pathUserList:
+			fmt.Fprint(w, `{"errcode":0,"userlist":[{"userid":"a","name":"A","department":[2],"extattr":{"attrs":[{"type":0,"name":"k","text":{"value":"v"}}]}}]}`)
+		case pathUserGet:
+			fmt.Fprintf(w, `{"errcode":0,"userid":%q,"department":[99]}`, r.URL.Query().Get("userid"))
+		default:
+			t.Errorf("unexpected request: %s", r.URL.Path)
+		}
+	})
+	return c
+}
+
+func TestDirectoryMirrorCopies(t *testing.T) {
+	m := newMirrorTestClient(t).Address.NewDirectoryMirror(1)
+	if err := m.Bootstrap(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	u, err := m.GetMember("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	u.Department[0] = 100
+	u.Extattr.Attrs[0].Text.Value = "changed"
+	members := m.Members()
+	members[0].Department[0] = 200
+
+	var received []DirectoryChange
+	m.Subscribe(func(c DirectoryChange) { received = append(received, c) })
+	m.HandleEvent(&ChangeContactEvent{ChangeType: ChangeUpdateUser, UserID: "a", Name: "A2"})
+	received[0].User.Department[0] = 300
+
+	u, _ = m.GetMember("a")
+	if u.Department[0] != 2 || u.Extattr.Attrs[0].Text.Value != "v" || u.Name != "A2" {
+		t.Fatalf("mirror modified through a copy: %+v", u)
+	}
+}
+
+func TestDirectoryMirrorOutOfScope(t *testing.T) {
+	m := newMirrorTestClient(t).Address.NewDirectoryMirror(1)
+	if err := m.Bootstrap(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.GetMember("outside"); err != nil {
+		t.Fatal(err)
+	}
+	var received []DirectoryChange
+	m.Subscribe(func(c DirectoryChange) { received = append(received, c) })
+	if err := m.Reconcile(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(received) != 0 {
+		t.Fatalf("got changes %+v, want none", received)
+	}
+	if len(m.Members()) != 1 {
+		t.Fatalf("got %d members, want 1", len(m.Members()))
+	}
+}
+
+func TestDirectoryMirrorRunInterval(t *testing.T) {
+	m := (&addressService{}).NewDirectoryMirror(1)
+	if err := m.Run(context.Background(), 0, nil); err == nil {
+		t.Fatal("want error for zero interval")
+	}
+}
+
+func TestDirectoryMirrorEventScope(t *testing.T) {
+	m := newMirrorTestClient(t).Address.NewDirectoryMirror(1)
+	if err := m.Bootstrap(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	var received []DirectoryChange
+	m.Subscribe(func(c DirectoryChange) { received = append(received, c) })
+
+	m.HandleEvent(&ChangeContactEvent{ChangeType: ChangeCreateUser, UserID: "x", Department: "99"})
+	m.HandleEvent(&ChangeContactEvent{ChangeType: ChangeCreateParty, ID: 50, ParentID: 99})
+	if len(received) != 0 || len(m.Members()) != 1 {
+		t.Fatalf("out-of-scope events applied: %+v", received)
+	}
+	if _, ok := m.Department(50); ok {
+		t.Fatal("out-of-scope department applied")
+	}
+
+	m.HandleEvent(&ChangeContactEvent{ChangeType: ChangeUpdateUser, UserID: "a", Department: "99"})
+	if len(received) != 1 || received[0].Type != ChangeDeleteUser || len(m.Members()) != 0 {
+		t.Fatalf("user moved out of scope not removed: %+v", received)
+	}
+}
+
+func TestDirectoryMirrorReconcileReplaysEvents(t *testing.T) {
+	var m *DirectoryMirror
+	c, _ := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case pathDepartmentList:
+			fmt.Fprint(w, `{"errcode":0,"department":[{"id":1,"name":"root"},{"id":2,"name":"dev","parentid":1}]}`)
+		case pathUserList:
+			// 在返回数据之前收到回调，返回的数据不包含该变更
+			m.HandleEvent(&ChangeContactEvent{ChangeType: ChangeUpdateUser, UserID: "a", Name: "A2"})
+			fmt.Fprint(w, `{"errcode":0,"userlist":[{"userid":"a","name":"A","department":[2]}]}`)
+		default:
+			t.Errorf("unexpected request: %s", r.URL.Path)
+		}
+	})
+	m = c.Address.NewDirectoryMirror(1)
+	m.users["a"] = &User{Userid: "a", Name: "A", Department: []int{2}}
+	m.departments[1] = &Department{ID: 1, Name: "root"}
+	m.departments[2] = &Department{ID: 2, Name: "dev", Parentid: 1}
+
+	var received []DirectoryChange
+	m.Subscribe(func(c DirectoryChange) { received = append(received, c) })
+	if err := m.Reconcile(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if u, _ := m.GetMember("a"); u.Name != "A2" {
+		t.Fatalf("event received during reconcile lost: %+v", u)
+	}
+	if len(received) != 1 || received[0].Source != "callback" {
+		t.Fatalf("got changes %+v, want only the callback", received)
+	}
+}
@@ -0,0 +1,99 @@
+// address_book_resolve.go 批量将手机号、邮箱、openid 转换为 userid
+package wecom
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+const defaultResolveWorkers = 4
+
+// 身份标识类型
+type IdentityType int
+
+const (
+	IdentityMobile IdentityType = iota + 1 // 手机号
+	IdentityEmail                          // 邮箱，默认按企业邮箱查询
+	IdentityOpenID                         // openid
+)
+
+// Identity 待转换的身份标识
+type Identity struct {
+	Type  IdentityType
+	Value string
+}
+
+// MobileIdentity 通过手机号标识成员
+func MobileIdentity(mobile string) Identity {
+	return Identity{Type: IdentityMobile, Value: mobile}
+}
+
+// EmailIdentity 通过企业邮箱标识成员
+func EmailIdentity(email string) Identity {
+	return Identity{Type: IdentityEmail, Value: email}
+}
+
+// OpenIDIdentity 通过 openid 标识成员
+func OpenIDIdentity(openID string) Identity {
+	return Identity{Type: IdentityOpenID, Value: openID}
+}
+
+// ParseIdentity 根据内容猜测标识类型：包含 @ 的视为邮箱，否则视为手机号
+func ParseIdentity(s string) Identity {
+	if strings.Contains(s, "@") {
+		return EmailIdentity(s)
+	}
+	return MobileIdentity(s)
+}
+
+// IdentityResult 单个标识的转换结果，Err 不为 nil 表示转换失败
+type IdentityResult struct {
+	Identity Identity
+	Userid   string
+	Err      error
+}
+
+// ResolveUserIDs 通讯录：并发地将一批身份标识转换为 userid
+// workers 为最大并发数，填 0 时使用默认值；返回结果与 identities 顺序一一对应
+// 单个标识转换失败不会影响其他标识，失败原因记录在 IdentityResult.Err 中
+func (b *addressService) ResolveUserIDs(ctx context.Context, identities []Identity, workers int) []IdentityResult {
+	if workers <= 0 {
+		workers = defaultResolveWorkers
+	}
+	s := b.WithContext(ctx)
+	results := make([]IdentityResult, len(identities))
+	errs := parallel(ctx, len(identities), workers, func(k int) (err error) {
+		results[k].Userid, err = s.resolveUserID(identities[k])
+		return err
+	})
+	for k := range identities {
+		results[k].Identity = identities[k]
+		results[k].Err = errs[k]
+	}
+	return results
+}
+
+func (b *addressService) resolveUserID(identity Identity) (string, error) {
+	var (
+		result *UserIDResp
+		err    error
+	)
+	switch identity.Type {
+	case IdentityMobile:
+		result, err = b.GetUserIDByMobile(identity.Value)
+	case IdentityEmail:
+		result, err = b.GetUserIDByEmail(identity.Value, EmailTypeCorp)
+	case IdentityOpenID:
+		result, err = b.ConvertToUserID(identity.Value)
+	default:
+		return "", fmt.Errorf("invalid identity type: %d", identity.Type)
+	}
+	if err != nil {
+		return "", err
+	}
+	if err = checkResponse(result); err != nil {
+		return "", err
+	}
+	return result.Userid, nil
+}
@@ -0,0 +1,528 @@
+// address_book_sync.go 将外部数据源（如 HR 系统）的组织架构同步到企业微信
+// 计算期望状态与当前状态的差异，生成变更计划，并按依赖顺序执行
+package wecom
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 变更类型，按执行顺序排列
+type SyncAction string
+
+const (
+	SyncCreateDepartment SyncAction = "create_department"
+	SyncUpdateDepartment SyncAction = "update_department"
+	SyncCreateUser       SyncAction = "create_user"
+	SyncUpdateUser       SyncAction = "update_user"
+	SyncMoveUser         SyncAction = "move_user" // 所属部门发生变化，可能同时包含其他字段的变化
+	SyncDisableUser      SyncAction = "disable_user"
+	SyncDeleteUser       SyncAction = "delete_user"
+	SyncDeleteDepartment SyncAction = "delete_department"
+)
+
+var syncActionOrder = map[SyncAction]int{
+	SyncCreateDepartment: 0,
+	SyncUpdateDepartment: 1,
+	SyncCreateUser:       2,
+	SyncUpdateUser:       3,
+	SyncMoveUser:         3,
+	SyncDisableUser:      4,
+	SyncDeleteUser:       4,
+	SyncDeleteDepartment: 5,
+}
+
+// Directory 组织架构快照，Departments 中的部门必须指定 ID
+type Directory struct {
+	Departments []Department
+	Users       []User
+}
+
+type SyncOptions struct {
+	// 同步范围的根部门 ID，默认为 1；根部门自身不会被创建或删除
+	RootDepartmentID int
+	// 对于不在期望状态中的成员，禁用而不是删除；被禁用成员所在的部门不会被删除
+	DisableInsteadOfDelete bool
+	// 保留不在期望状态中的部门
+	KeepExtraDepartments bool
+	// 每分钟最多执行的变更数，0 表示不额外限流（仍受 Client 限流器约束）
+	RatePerMinute int
+	// 遇到失败时继续执行后续变更，默认遇到失败即停止
+	ContinueOnError bool
+}
+
+func (o *SyncOptions) rootID() int {
+	if o == nil || o.RootDepartmentID == 0 {
+		return 1
+	}
+	return o.RootDepartmentID
+}
+
+// SyncChange 一项变更
+type SyncChange struct {
+	Action     SyncAction
+	Department *Department
+	// 对于更新类变更，User 仅包含 Userid 和需要修改的字段
+	User *User
+	// 发生变化的字段，对应 json 字段名
+	Fields []string
+	// 变更前的所属部门，仅 SyncMoveUser 有效
+	FromDepartment []int
+}
+
+func (c SyncChange) String() string {
+	switch c.Action {
+	case SyncCreateDepartment, SyncUpdateDepartment, SyncDeleteDepartment:
+		s := fmt.Sprintf("%s %d(%s)", c.Action, c.Department.ID, c.Department.Name)
+		if len(c.Fields) > 0 {
+			s += " fields: " + strings.Join(c.Fields, ",")
+		}
+		return s
+	case SyncMoveUser:
+		return fmt.Sprintf("%s %s %v -> %v fields: %s", c.Action, c.User.Userid, c.FromDepartment, c.User.Department, strings.Join(c.Fields, ","))
+	default:
+		s := fmt.Sprintf("%s %s", c.Action, c.User.Userid)
+		if len(c.Fields) > 0 {
+			s += " fields: " + strings.Join(c.Fields, ",")
+		}
+		return s
+	}
+}
+
+// SyncPlan 变更计划，Changes 已按依赖顺序排列
+type SyncPlan struct {
+	Changes []SyncChange
+}
+
+// String 输出可读的变更计划，可用于 dry-run
+func (p *SyncPlan) String() string {
+	if len(p.Changes) == 0 {
+		return "no changes\n"
+	}
+	b := &strings.Builder{}
+	for k := range p.Changes {
+		fmt.Fprintf(b, "%d. %s\n", k+1, p.Changes[k])
+	}
+	return b.String()
+}
+
+// SyncResult 单项变更的执行结果，Skipped 表示因前序失败或 Context 取消而未执行
+type SyncResult struct {
+	Change  SyncChange
+	Err     error
+	Skipped bool
+}
+
+// SyncReport 同步报告
+type SyncReport struct {
+	Results   []SyncResult
+	Succeeded int
+	Failed    int
+	Skipped   int
+}
+
+func (r *SyncReport) String() string {
+	b := &strings.Builder{}
+	fmt.Fprintf(b, "succeeded: %d, failed: %d, skipped: %d\n", r.Succeeded, r.Failed, r.Skipped)
+	for _, result := range r.Results {
+		if result.Err != nil && !result.Skipped {
+			fmt.Fprintf(b, "failed: %s, err: %v\n", result.Change, result.Err)
+		}
+	}
+	return b.String()
+}
+
+// CurrentDirectory 通讯录：获取 rootDepartmentID 下的部门和成员
+// 成员优先通过 ListMemberDetail 获取；新创建的应用无法调用该接口（48002、60011），此时改为通过 MemberIterator 及 GetMember 逐个获取，
+// 其他错误（如频率超限）直接返回
+func (b *addressService) CurrentDirectory(rootDepartmentID int) (*Directory, error) {
+	departments, err := b.DepartmentList(rootDepartmentID)
+	if err != nil {
+		return nil, err
+	}
+	if err = checkResponse(departments); err != nil {
+		return nil, err
+	}
+	dir := &Directory{Departments: departments.Department}
+
+	users, err := b.ListMemberDetail(rootDepartmentID, 1)
+	if err == nil {
+		err = checkResponse(users)
+	}
+	var e *Error
+	if err == nil {
+		dir.Users = users.Userlist
+	} else if errors.As(err, &e) && (e.ErrCode == errCodeAPIForbidden || e.ErrCode == errCodeNoPrivilege) {
+		dir.Users, err = b.membersOf(departments.Department)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return dir, nil
+}
+
+// membersOf 通过 user/list_id 及 user/get 获取 departments 中的成员，顺序与 user/list_id 一致
+func (b *addressService) membersOf(departments []Department) ([]User, error) {
+	inScope := make(map[int]struct{}, len(departments))
+	for _, d := range departments {
+		inScope[d.ID] = struct{}{}
+	}
+	it := b.MemberIterator(0)
+	seen := make(map[string]struct{})
+	var ids []string
+	for it.Next() {
+		m := it.Member()
+		if _, ok := inScope[m.Department]; !ok {
+			continue
+		}
+		if _, ok := seen[m.Userid]; ok {
+			continue
+		}
+		seen[m.Userid] = struct{}{}
+		ids = append(ids, m.Userid)
+	}
+	if err := it.Err(); err != nil {
+		return nil, err
+	}
+
+	users := make([]User, len(ids))
+	errs := parallel(b.ctx, len(ids), defaultResolveWorkers, func(k int) error {
+		return retryOnFreqLimit(b.ctx, func() error {
+			user, err := b.GetMember(ids[k])
+			if err == nil {
+				err = checkResponse(user)
+			}
+			if err == nil {
+				users[k] = *user
+			}
+			return err
+		})
+	})
+	for k, err := range errs {
+		if err != nil {
+			return nil, fmt.Errorf("get member %s: %w", ids[k], err)
+		}
+	}
+	return users, nil
+}
+
+// PlanSync 通讯录：获取当前状态，并计算与期望状态 desired 的差异
+func (b *addressService) PlanSync(desired *Directory, opts *SyncOptions) (*SyncPlan, error) {
+	current, err := b.CurrentDirectory(opts.rootID())
+	if err != nil {
+		return nil, err
+	}
+	return DiffDirectory(current, desired, opts), nil
+}
+
+// Sync 通讯录：计算差异并执行，dryRun 为 true 时只返回变更计划
+func (b *addressService) Sync(ctx context.Context, desired *Directory, opts *SyncOptions, dryRun bool) (*SyncPlan, *SyncReport, error) {
+	plan, err := b.WithContext(ctx).PlanSync(desired, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	if dryRun {
+		return plan, nil, nil
+	}
+	return plan, b.ApplySync(ctx, plan, opts), nil
+}
+
+// DiffDirectory 计算从 current 到 desired 所需的变更
+// 成员字段只比较 desired 中的非零值，即 desired 中未填写的字段视为不关心
+func DiffDirectory(current, desired *Directory, opts *SyncOptions) *SyncPlan {
+	if opts == nil {
+		opts = &SyncOptions{}
+	}
+	root := opts.rootID()
+	plan := &SyncPlan{}
+
+	// 部门
+	currentDept := make(map[int]Department, len(current.Departments))
+	for _, d := range current.Departments {
+		currentDept[d.ID] = d
+	}
+	desiredDept := make(map[int]Department, len(desired.Departments))
+	for _, d := range desired.Departments {
+		desiredDept[d.ID] = d
+	}
+	for _, d := range desired.Departments {
+		if d.ID == root {
+			continue
+		}
+		d := d
+		cur, ok := currentDept[d.ID]
+		if !ok {
+			plan.Changes = append(plan.Changes, SyncChange{Action: SyncCreateDepartment, Department: &d})
+			continue
+		}
+		var fields []string
+		if d.Name != "" && d.Name != cur.Name {
+			fields = append(fields, "name")
+		}
+		if d.Parentid != 0 && d.Parentid != cur.Parentid {
+			fields = append(fields, "parentid")
+		}
+		if d.Order != 0 && d.Order != cur.Order {
+			fields = append(fields, "order")
+		}
+		if len(fields) > 0 {
+			plan.Changes = append(plan.Changes, SyncChange{Action: SyncUpdateDepartment, Department: &d, Fields: fields})
+		}
+	}
+	if !opts.KeepExtraDepartments {
+		// 被禁用的成员仍然属于原部门，这些部门及其上级部门无法删除
+		kept := make(map[int]struct{})
+		if opts.DisableInsteadOfDelete {
+			parent := make(map[int]int, len(current.Departments))
+			for _, d := range current.Departments {
+				parent[d.ID] = d.Parentid
+			}
+			desiredUser := make(map[string]struct{}, len(desired.Users))
+			for k := range desired.Users {
+				desiredUser[desired.Users[k].Userid] = struct{}{}
+			}
+			for k := range current.Users {
+				if _, ok := desiredUser[current.Users[k].Userid]; ok {
+					continue
+				}
+				for _, id := range current.Users[k].Department {
+					for n := 0; id != 0 && n <= len(parent); n++ {
+						kept[id] = struct{}{}
+						id = parent[id]
+					}
+				}
+			}
+		}
+		for _, d := range current.Departments {
+			if _, ok := desiredDept[d.ID]; ok || d.ID == root {
+				continue
+			}
+			if _, ok := kept[d.ID]; ok {
+				continue
+			}
+			d := d
+			plan.Changes = append(plan.Changes, SyncChange{Action: SyncDeleteDepartment, Department: &d})
+		}
+	}
+
+	// 成员
+	currentUser := make(map[string]*User, len(current.Users))
+	for k := range current.Users {
+		currentUser[current.Users[k].Userid] = &current.Users[k]
+	}
+	desiredUser := make(map[string]struct{}, len(desired.Users))
+	for k := range desired.Users {
+		d := &desired.Users[k]
+		desiredUser[d.Userid] = struct{}{}
+		cur, ok := currentUser[d.Userid]
+		if !ok {
+			plan.Changes = append(plan.Changes, SyncChange{Action: SyncCreateUser, User: d})
+			continue
+		}
+		if change, ok := diffUser(cur, d); ok {
+			plan.Changes = append(plan.Changes, change)
+		}
+	}
+	for k := range current.Users {
+		cur := &current.Users[k]
+		if _, ok := desiredUser[cur.Userid]; ok {
+			continue
+		}
+		if !opts.DisableInsteadOfDelete {
+			plan.Changes = append(plan.Changes, SyncChange{Action: SyncDeleteUser, User: &User{Userid: cur.Userid}})
+			continue
+		}
+		if cur.Enable != nil && *cur.Enable == 0 {
+			continue // 已禁用
+		}
+		disable := 0
+		plan.Changes = append(plan.Changes, SyncChange{
+			Action: SyncDisableUser,
+			User:   &User{Userid: cur.Userid, Enable: &disable},
+			Fields: []string{"enable"},
+		})
+	}
+
+	sortSyncChanges(plan.Changes, current, desired)
+	return plan
+}
+
+// diffUser 比较 desired 中的非零字段，返回只包含变化字段的更新
+func diffUser(cur, desired *User) (SyncChange, bool) {
+	update := &User{Userid: desired.Userid}
+	uv := reflect.ValueOf(update).Elem()
+	cv := reflect.ValueOf(cur).Elem()
+	dv := reflect.ValueOf(desired).Elem()
+	t := dv.Type()
+
+	var fields []string
+	moved := false
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name := strings.Split(f.Tag.Get("json"), ",")[0]
+		if f.Anonymous || name == "" || name == "-" || name == "userid" || name == "to_invite" {
+			continue
+		}
+		if _, ok := userReadonlyFields[name]; ok {
+			continue
+		}
+		d := dv.Field(i)
+		if d.IsZero() {
+			continue
+		}
+		if name == "department" {
+			if sameIntSet(cur.Department, desired.Department) {
+				continue
+			}
+			moved = true
+		} else if reflect.DeepEqual(d.Interface(), cv.Field(i).Interface()) {
+			continue
+		}
+		uv.Field(i).Set(d)
+		fields = append(fields, name)
+	}
+	if len(fields) == 0 {
+		return SyncChange{}, false
+	}
+	change := SyncChange{Action: SyncUpdateUser, User: update, Fields: fields}
+	if moved {
+		change.Action = SyncMoveUser
+		change.FromDepartment = cur.Department
+		// order、is_leader_in_dept 与 department 一一对应，需要一并提交
+		if update.Order == nil {
+			update.Order = desired.Order
+		}
+		if update.IsLeaderInDept == nil {
+			update.IsLeaderInDept = desired.IsLeaderInDept
+		}
+	}
+	return change, true
+}
+
+func sameIntSet(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	m := make(map[int]int, len(a))
+	for _, v := range a {
+		m[v]++
+	}
+	for _, v := range b {
+		if m[v] == 0 {
+			return false
+		}
+		m[v]--
+	}
+	return true
+}
+
+// sortSyncChanges 按依赖顺序排列：先创建上级部门，最后删除下级部门
+func sortSyncChanges(changes []SyncChange, current, desired *Directory) {
+	parent := make(map[int]int)
+	for _, d := range current.Departments {
+		parent[d.ID] = d.Parentid
+	}
+	for _, d := range desired.Departments {
+		parent[d.ID] = d.Parentid
+	}
+	depth := func(id int) int {
+		n := 0
+		for p, ok := parent[id]; ok && p != 0 && n <= len(parent); p, ok = parent[p] {
+			n++
+		}
+		return n
+	}
+
+	sort.SliceStable(changes, func(i, j int) bool {
+		a, b := changes[i], changes[j]
+		if syncActionOrder[a.Action] != syncActionOrder[b.Action] {
+			return syncActionOrder[a.Action] < syncActionOrder[b.Action]
+		}
+		switch a.Action {
+		case SyncCreateDepartment, SyncUpdateDepartment:
+			return depth(a.Department.ID) < depth(b.Department.ID)
+		case SyncDeleteDepartment:
+			return depth(a.Department.ID) > depth(b.Department.ID)
+		}
+		return false
+	})
+}
+
+// ApplySync 通讯录：按顺序执行变更计划
+func (b *addressService) ApplySync(ctx context.Context, plan *SyncPlan, opts *SyncOptions) *SyncReport {
+	if opts == nil {
+		opts = &SyncOptions{}
+	}
+	s := b.WithContext(ctx)
+	var limiter *rateLimiter
+	if opts.RatePerMinute > 0 {
+		limiter = newRateLimiter(opts.RatePerMinute, time.Minute)
+	}
+
+	report := &SyncReport{Results: make([]SyncResult, 0, len(plan.Changes))}
+	stop := false
+	for _, change := range plan.Changes {
+		result := SyncResult{Change: change}
+		if stop {
+			result.Skipped = true
+			report.Skipped++
+			report.Results = append(report.Results, result)
+			continue
+		}
+		if limiter != nil {
+			result.Err = limiter.wait(ctx)
+		} else if ctx != nil {
+			result.Err = ctx.Err()
+		}
+		if result.Err != nil {
+			// Context 已取消，后续变更均不执行
+			result.Skipped = true
+			report.Skipped++
+			stop = true
+			report.Results = append(report.Results, result)
+			continue
+		}
+
+		result.Err = s.applySyncChange(change)
+		if result.Err != nil {
+			report.Failed++
+			stop = !opts.ContinueOnError
+		} else {
+			report.Succeeded++
+		}
+		report.Results = append(report.Results, result)
+	}
+	return report
+}
+
+func (b *addressService) applySyncChange(change SyncChange) error {
+	var (
+		result iBaseResponse
+		err    error
+	)
+	switch change.Action {
+	case SyncCreateDepartment:
+		result, err = b.CreateDepartment(change.Department)
+	case SyncUpdateDepartment:
+		result, err = b.UpdateDepartment(change.Department)
+	case SyncDeleteDepartment:
+		result, err = b.DeleteDepartment(change.Department.ID)
+	case SyncCreateUser:
+		result, err = b.CreateMember(change.User)
+	case SyncUpdateUser, SyncMoveUser, SyncDisableUser:
+		result, err = b.UpdateMember(change.User)
+	case SyncDeleteUser:
+		result, err = b.DeleteMember(change.User.Userid)
+	default:
+		return fmt.Errorf("invalid sync action: %s", change.Action)
+	}
+	if err != nil {
+		return err
+	}
+	return checkResponse(result)
+}
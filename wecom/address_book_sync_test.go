@@ -0,0 +1,115 @@
+package wecom
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestDiffDirectory(t *testing.T) {
+	current := &Directory{
+		Departments: []Department{
+			{ID: 1, Name: "root"},
+			{ID: 2, Name: "dev", Parentid: 1},
+			{ID: 3, Name: "ops", Parentid: 1},
+			{ID: 4, Name: "old", Parentid: 1},
+		},
+		Users: []User{
+			{Userid: "a", Name: "A", Department: []int{2}},
+			{Userid: "b", Name: "B", Department: []int{2}},
+			{Userid: "c", Name: "C", Department: []int{3}},
+		},
+	}
+	desired := &Directory{
+		Departments: []Department{
+			{ID: 1, Name: "root"},
+			{ID: 2, Name: "develop", Parentid: 1},
+			{ID: 3, Name: "ops", Parentid: 1},
+			{ID: 5, Name: "new", Parentid: 1},
+		},
+		Users: []User{
+			{Userid: "a", Name: "A", Department: []int{2}},
+			{Userid: "b", Name: "B2", Department: []int{5}},
+			{Userid: "d", Name: "D", Department: []int{5}},
+		},
+	}
+
+	plan := DiffDirectory(current, desired, nil)
+	var got []string
+	for _, c := range plan.Changes {
+		got = append(got, c.String())
+	}
+	want := []string{
+		"create_department 5(new)",
+		"update_department 2(develop) fields: name",
+		"create_user d",
+		"move_user b [2] -> [5] fields: name,department",
+		"delete_user c",
+		"delete_department 4(old)",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got plan:\n%s", plan)
+	}
+
+	// 禁用而不是删除时，被禁用成员所在的部门不会被删除
+	current.Users[2].Department = []int{4}
+	plan = DiffDirectory(current, desired, &SyncOptions{DisableInsteadOfDelete: true})
+	for _, c := range plan.Changes {
+		if c.Action == SyncDeleteDepartment || c.Action == SyncDeleteUser {
+			t.Fatalf("unexpected change: %s", c)
+		}
+	}
+	if last := plan.Changes[len(plan.Changes)-1]; last.Action != SyncDisableUser || *last.User.Enable != 0 {
+		t.Fatalf("got last change %s, want disable_user c", last)
+	}
+}
+
+func TestCurrentDirectoryFallback(t *testing.T) {
+	c, _ := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case pathDepartmentList:
+			fmt.Fprint(w, `{"errcode":0,"department":[{"id":2,"name":"dev","parentid":1},{"id":3,"name":"qa","parentid":2}]}`)
+		case pathUserList:
+			fmt.Fprint(w, `{"errcode":48002,"errmsg":"api forbidden"}`)
+		case pathUserListID:
+			var body listMemberID
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			if body.Cursor == "" {
+				fmt.Fprint(w, `{"errcode":0,"next_cursor":"c1","dept_user":[{"userid":"a","department":2},{"userid":"x","department":9}]}`)
+				return
+			}
+			fmt.Fprint(w, `{"errcode":0,"dept_user":[{"userid":"b","department":3},{"userid":"a","department":3}]}`)
+		case pathUserGet:
+			id := r.URL.Query().Get("userid")
+			fmt.Fprintf(w, `{"errcode":0,"userid":%q,"name":"name-%s"}`, id, id)
+		default:
+			t.Errorf("unexpected request: %s", r.URL.Path)
+		}
+	})
+	dir, err := c.Address.CurrentDirectory(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dir.Departments) != 2 || len(dir.Users) != 2 || dir.Users[0].Name != "name-a" || dir.Users[1].Userid != "b" {
+		t.Fatalf("unexpected directory: %+v", dir)
+	}
+}
+
+func TestCurrentDirectoryNoFallbackOnOtherErrors(t *testing.T) {
+	c, _ := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case pathDepartmentList:
+			fmt.Fprint(w, `{"errcode":0,"department":[{"id":2,"name":"dev","parentid":1}]}`)
+		case pathUserList:
+			fmt.Fprint(w, `{"errcode":45009,"errmsg":"api freq out of limit"}`)
+		default:
+			t.Errorf("unexpected request: %s", r.URL.Path)
+		}
+	})
+	_, err := c.Address.CurrentDirectory(2)
+	if !isFreqLimitErr(err) {
+		t.Fatalf("got err %v, want errcode 45009", err)
+	}
+}
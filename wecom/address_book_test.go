@@ -0,0 +1,39 @@
+package wecom
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestBatchDeleteMemberPartialFailure(t *testing.T) {
+	batches := 0
+	c, _ := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		var body batchDelete
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		if len(body.UseridList) > batchDeleteLimit {
+			t.Errorf("got %d userids in one batch", len(body.UseridList))
+		}
+		batches++
+		if batches == 2 {
+			// 模拟网关断开连接
+			hj, _ := w.(http.Hijacker)
+			conn, _, _ := hj.Hijack()
+			_ = conn.Close()
+			return
+		}
+		fmt.Fprint(w, `{"errcode":0}`)
+	})
+	userIDs := make([]string, 450)
+	for i := range userIDs {
+		userIDs[i] = fmt.Sprint("u", i)
+	}
+	result, err := c.Address.BatchDeleteMember(userIDs)
+	if err == nil {
+		t.Fatal("want transport error")
+	}
+	if result == nil || result.Deleted != 200 {
+		t.Fatalf("got %+v, want 200 deleted", result)
+	}
+}
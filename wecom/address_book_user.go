@@ -0,0 +1,268 @@
+// address_book_user.go 成员扩展属性的辅助方法，以及按字段更新成员
+package wecom
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+)
+
+// NewTextAttr 文本类型的扩展属性
+func NewTextAttr(name, value string) Attrs {
+	return Attrs{Type: AttrTypeText, Name: name, Text: &Text{Value: value}}
+}
+
+// NewWebAttr 网页类型的扩展属性
+func NewWebAttr(name, url, title string) Attrs {
+	return Attrs{Type: AttrTypeWeb, Name: name, Web: &Web{URL: url, Title: title}}
+}
+
+// NewMiniprogramAttr 小程序类型的扩展属性
+func NewMiniprogramAttr(name, appid, pagepath, title string) Attrs {
+	return Attrs{Type: AttrTypeMiniprogram, Name: name, Miniprogram: &Miniprogram{Appid: appid, Pagepath: pagepath, Title: title}}
+}
+
+// Get 按名称查找扩展属性
+func (e *Extattr) Get(name string) (*Attrs, bool) {
+	if e == nil {
+		return nil, false
+	}
+	for k := range e.Attrs {
+		if e.Attrs[k].Name == name {
+			return &e.Attrs[k], true
+		}
+	}
+	return nil, false
+}
+
+// SetExtattr 设置扩展属性，同名属性存在时替换；成员没有扩展属性时会自动创建
+func (u *User) SetExtattr(attr Attrs) {
+	if u.Extattr == nil {
+		u.Extattr = &Extattr{}
+	}
+	if a, ok := u.Extattr.Get(attr.Name); ok {
+		*a = attr
+		return
+	}
+	u.Extattr.Attrs = append(u.Extattr.Attrs, attr)
+}
+
+// Delete 删除同名扩展属性
+func (e *Extattr) Delete(name string) {
+	if e == nil {
+		return
+	}
+	attrs := e.Attrs[:0]
+	for _, a := range e.Attrs {
+		if a.Name != name {
+			attrs = append(attrs, a)
+		}
+	}
+	e.Attrs = attrs
+}
+
+// Text 读取文本类型的扩展属性
+func (e *Extattr) Text(name string) (string, bool) {
+	a, ok := e.Get(name)
+	if !ok || a.Type != AttrTypeText || a.Text == nil {
+		return "", false
+	}
+	return a.Text.Value, true
+}
+
+// Web 读取网页类型的扩展属性
+func (e *Extattr) Web(name string) (*Web, bool) {
+	a, ok := e.Get(name)
+	if !ok || a.Type != AttrTypeWeb || a.Web == nil {
+		return nil, false
+	}
+	return a.Web, true
+}
+
+// Miniprogram 读取小程序类型的扩展属性
+func (e *Extattr) Miniprogram(name string) (*Miniprogram, bool) {
+	a, ok := e.Get(name)
+	if !ok || a.Type != AttrTypeMiniprogram || a.Miniprogram == nil {
+		return nil, false
+	}
+	return a.Miniprogram, true
+}
+
+// Clone 深拷贝成员，副本与原成员不共享切片及指针字段
+func (u *User) Clone() *User {
+	if u == nil {
+		return nil
+	}
+	c := *u
+	c.Department = cloneInts(u.Department)
+	c.Order = cloneInts(u.Order)
+	c.IsLeaderInDept = cloneInts(u.IsLeaderInDept)
+	if u.DirectLeader != nil {
+		c.DirectLeader = append([]string{}, u.DirectLeader...)
+	}
+	if u.Enable != nil {
+		enable := *u.Enable
+		c.Enable = &enable
+	}
+	if u.ToInvite != nil {
+		toInvite := *u.ToInvite
+		c.ToInvite = &toInvite
+	}
+	if u.Extattr != nil {
+		c.Extattr = &Extattr{}
+		for _, a := range u.Extattr.Attrs {
+			c.Extattr.Attrs = append(c.Extattr.Attrs, cloneAttrs(a))
+		}
+	}
+	if u.ExternalProfile != nil {
+		p := &ExternalProfile{ExternalCorpName: u.ExternalProfile.ExternalCorpName}
+		for _, a := range u.ExternalProfile.ExternalAttr {
+			p.ExternalAttr = append(p.ExternalAttr, ExternalAttr(cloneAttrs(Attrs(a))))
+		}
+		c.ExternalProfile = p
+	}
+	return &c
+}
+
+func cloneInts(s []int) []int {
+	if s == nil {
+		return nil
+	}
+	return append([]int{}, s...)
+}
+
+func cloneAttrs(a Attrs) Attrs {
+	if a.Text != nil {
+		text := *a.Text
+		a.Text = &text
+	}
+	if a.Web != nil {
+		web := *a.Web
+		a.Web = &web
+	}
+	if a.Miniprogram != nil {
+		mp := *a.Miniprogram
+		a.Miniprogram = &mp
+	}
+	return a
+}
+
+// 只读字段，不能通过更新接口修改
+var userReadonlyFields = map[string]struct{}{
+	"avatar":       {},
+	"thumb_avatar": {},
+	"open_userid":  {},
+	"status":       {},
+	"qr_code":      {},
+}
+
+// 不能清空的字段，按字段更新时必须显式赋值，否则零值会被提交，如 enable 为 0 会禁用成员
+var userUnclearableFields = map[string]struct{}{
+	"enable":          {},
+	"to_invite":       {},
+	"main_department": {},
+	"gender":          {},
+}
+
+// userMaskBody 根据 mask 生成更新成员的请求体，mask 中的字段即使为空也会提交
+func userMaskBody(user *User, mask []string) (map[string]interface{}, error) {
+	fields := make(map[string]reflect.Value)
+	v := reflect.ValueOf(user).Elem()
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		name := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]
+		if t.Field(i).Anonymous || name == "" || name == "-" {
+			continue
+		}
+		fields[name] = v.Field(i)
+	}
+
+	body := map[string]interface{}{"userid": user.Userid}
+	for _, name := range mask {
+		if name == "userid" {
+			continue
+		}
+		if _, ok := userReadonlyFields[name]; ok {
+			return nil, fmt.Errorf("readonly field: %s", name)
+		}
+		f, ok := fields[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown field: %s", name)
+		}
+		if _, ok := userUnclearableFields[name]; ok && f.IsZero() {
+			return nil, fmt.Errorf("field %s cannot be cleared, a value is required", name)
+		}
+		body[name] = jsonValueOrEmpty(f)
+	}
+	return body, nil
+}
+
+// jsonValueOrEmpty 返回字段的值，nil 的切片、指针转换为对应的空值，以便清空该字段
+func jsonValueOrEmpty(v reflect.Value) interface{} {
+	switch v.Kind() {
+	case reflect.Slice:
+		if v.IsNil() {
+			return []interface{}{}
+		}
+	case reflect.Ptr:
+		if v.IsNil() {
+			return emptyJSONValue(v.Type().Elem())
+		}
+	}
+	return v.Interface()
+}
+
+// emptyJSONValue 生成类型 t 对应的 JSON 空值，结构体的每个字段都会被显式地置空
+func emptyJSONValue(t reflect.Type) interface{} {
+	switch t.Kind() {
+	case reflect.Ptr:
+		return emptyJSONValue(t.Elem())
+	case reflect.Slice:
+		return []interface{}{}
+	case reflect.Struct:
+		m := make(map[string]interface{})
+		for i := 0; i < t.NumField(); i++ {
+			name := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]
+			if name == "" || name == "-" {
+				continue
+			}
+			m[name] = emptyJSONValue(t.Field(i).Type)
+		}
+		return m
+	default:
+		return reflect.Zero(t).Interface()
+	}
+}
+
+// UpdateMemberMask 通讯录：按字段更新成员
+// 参考链接：https://developer.work.weixin.qq.com/document/path/90197
+// mask 为需要更新的字段，使用 json 字段名，如 "mobile"、"email"、"extattr"
+// 与 UpdateMember 不同，只有 mask 中的字段会被提交，且即使为空值也会提交，可用于清空字段
+// enable、to_invite、main_department、gender 不能清空，出现在 mask 中时必须赋值
+func (b *addressService) UpdateMemberMask(user *User, mask ...string) (result *UserResp, err error) {
+	body, err := userMaskBody(user, mask)
+	if err != nil {
+		return nil, err
+	}
+
+	failCount := -1
+	// 默认尝试一次，即不进行失败重试
+	for failCount < b.client.maxRetryTimes {
+		failCount++
+		var req *http.Request
+		req, err = b.client.newRequest(http.MethodPost, pathUserUpdate, body)
+		if err != nil {
+			continue // 如果循环结束，则会返回该 err
+		}
+		result = new(UserResp)
+		err = (*service)(b).doRequest(req, result)
+		if err != nil {
+			continue // 如果循环结束，则会返回该 err
+		}
+		// 成功，return
+		return result, nil
+	}
+	// 失败，返回最后一次请求的 err
+	return nil, err
+}
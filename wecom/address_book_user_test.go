@@ -0,0 +1,84 @@
+package wecom
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"testing"
+)
+
+func TestUserMaskBody(t *testing.T) {
+	disabled := 0
+	tests := []struct {
+		name    string
+		user    *User
+		mask    []string
+		want    string
+		wantErr bool
+	}{
+		{name: "clear mobile", user: &User{Userid: "u"}, mask: []string{"mobile"}, want: `{"mobile":"","userid":"u"}`},
+		{name: "clear extattr", user: &User{Userid: "u"}, mask: []string{"extattr"}, want: `{"extattr":{"attrs":[]},"userid":"u"}`},
+		{name: "clear department", user: &User{Userid: "u"}, mask: []string{"department"}, want: `{"department":[],"userid":"u"}`},
+		{name: "explicit disable", user: &User{Userid: "u", Enable: &disabled}, mask: []string{"enable"}, want: `{"enable":0,"userid":"u"}`},
+		{name: "nil enable", user: &User{Userid: "u"}, mask: []string{"enable"}, wantErr: true},
+		{name: "nil to_invite", user: &User{Userid: "u"}, mask: []string{"to_invite"}, wantErr: true},
+		{name: "zero main_department", user: &User{Userid: "u"}, mask: []string{"main_department"}, wantErr: true},
+		{name: "empty gender", user: &User{Userid: "u"}, mask: []string{"gender"}, wantErr: true},
+		{name: "readonly", user: &User{Userid: "u"}, mask: []string{"avatar"}, wantErr: true},
+		{name: "unknown", user: &User{Userid: "u"}, mask: []string{"nope"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, err := userMaskBody(tt.user, tt.mask)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("want error, got %v", body)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, _ := json.Marshal(body)
+			if string(got) != tt.want {
+				t.Fatalf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpdateMemberMaskDoesNotSendZeroEnable(t *testing.T) {
+	requests := 0
+	c, _ := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		_, _ = ioutil.ReadAll(r.Body)
+		_, _ = w.Write([]byte(`{"errcode":0}`))
+	})
+	if _, err := c.Address.UpdateMemberMask(&User{Userid: "x"}, "enable"); err == nil {
+		t.Fatal("want error for nil enable")
+	}
+	if requests != 0 {
+		t.Fatalf("got %d requests, want 0", requests)
+	}
+}
+
+func TestSetExtattr(t *testing.T) {
+	u := &User{}
+	u.SetExtattr(NewTextAttr("a", "1"))
+	u.SetExtattr(NewTextAttr("b", "2"))
+	u.SetExtattr(NewTextAttr("a", "3"))
+	if v, ok := u.Extattr.Text("a"); !ok || v != "3" {
+		t.Fatalf("got %q, want 3", v)
+	}
+	if len(u.Extattr.Attrs) != 2 {
+		t.Fatalf("got %d attrs, want 2", len(u.Extattr.Attrs))
+	}
+	u.Extattr.Delete("a")
+	if _, ok := u.Extattr.Get("a"); ok {
+		t.Fatal("attr a not deleted")
+	}
+	var empty *Extattr
+	if _, ok := empty.Text("a"); ok {
+		t.Fatal("nil extattr has no attrs")
+	}
+}
@@ -1,2 +1,223 @@
-// basic.go 对应的是 https://work.weixin.qq.com/api/doc/90000/90135/90226 文档内容
+// application_manager.go 对应的是 https://work.weixin.qq.com/api/doc/90000/90135/90226 文档内容
+// 主要实现了应用的查询及设置 API
 package wecom
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+const (
+	pathAgentGet  = "/cgi-bin/agent/get"
+	pathAgentList = "/cgi-bin/agent/list"
+	pathAgentSet  = "/cgi-bin/agent/set"
+)
+
+type agentService service
+
+func (a *agentService) WithContext(ctx context.Context) *agentService {
+	return &agentService{
+		client: a.client,
+		ctx:    ctx,
+	}
+}
+
+// resolveAgentID agentID 为 0 时使用 NewWithAgentIDOption 设置的应用 ID
+func (a *agentService) resolveAgentID(agentID int) (int, error) {
+	if agentID != 0 {
+		return agentID, nil
+	}
+	if a.client.agentID == 0 {
+		return 0, errors.New("agent id is required")
+	}
+	return a.client.agentID, nil
+}
+
+// 应用可见范围内的成员
+type AllowUserInfos struct {
+	User []struct {
+		Userid string `json:"userid"`
+	} `json:"user"`
+}
+
+// 应用可见范围内的部门
+type AllowPartys struct {
+	PartyID []int `json:"partyid"`
+}
+
+// 应用可见范围内的标签
+type AllowTags struct {
+	TagID []int `json:"tagid"`
+}
+
+// 应用详情
+type Agent struct {
+	baseResponse
+	AgentID       int    `json:"agentid"`
+	Name          string `json:"name"`
+	SquareLogoURL string `json:"square_logo_url"`
+	Description   string `json:"description"`
+	// 可见范围
+	AllowUserInfos AllowUserInfos `json:"allow_userinfos"`
+	AllowPartys    AllowPartys    `json:"allow_partys"`
+	AllowTags      AllowTags      `json:"allow_tags"`
+	// 应用是否被停用，0：否，1：是
+	Close int `json:"close"`
+	// 可信域名
+	RedirectDomain string `json:"redirect_domain"`
+	// 是否打开地理位置上报，0：不上报，1：进入会话上报
+	ReportLocationFlag int `json:"report_location_flag"`
+	// 是否上报用户进入应用事件，0：不接收，1：接收
+	IsReportEnter int    `json:"isreportenter"`
+	HomeURL       string `json:"home_url"`
+	// 代开发自建应用的发布状态
+	CustomizedPublishStatus int `json:"customized_publish_status"`
+}
+
+// UserIDs 可见范围内的成员 userid
+func (a *Agent) UserIDs() []string {
+	ids := make([]string, 0, len(a.AllowUserInfos.User))
+	for _, u := range a.AllowUserInfos.User {
+		ids = append(ids, u.Userid)
+	}
+	return ids
+}
+
+// 应用管理：获取指定的应用详情，agentID 为 0 时使用 NewWithAgentIDOption 设置的应用 ID
+// 参考链接：https://developer.work.weixin.qq.com/document/path/90227
+func (a *agentService) Get(agentID int) (result *Agent, err error) {
+	agentID, err = a.resolveAgentID(agentID)
+	if err != nil {
+		return nil, err
+	}
+	failCount := -1
+	// 默认尝试一次，即不进行失败重试
+	for failCount < a.client.maxRetryTimes {
+		failCount++
+		var req *http.Request
+		req, err = a.client.newRequest(http.MethodGet, pathAgentGet, nil, fmt.Sprintf("agentid=%d", agentID))
+		if err != nil {
+			continue // 如果循环结束，则会返回该 err
+		}
+		result = new(Agent)
+		err = (*service)(a).doRequest(req, result)
+		if err != nil {
+			continue // 如果循环结束，则会返回该 err
+		}
+		// 成功，return
+		return result, nil
+	}
+	// 失败，返回最后一次请求的 err
+	return nil, err
+}
+
+// 应用列表中的应用概况
+type AgentBrief struct {
+	AgentID       int    `json:"agentid"`
+	Name          string `json:"name"`
+	SquareLogoURL string `json:"square_logo_url"`
+}
+
+type AgentList struct {
+	baseResponse
+	AgentList []AgentBrief `json:"agentlist"`
+}
+
+// 应用管理：获取 access_token 对应的应用列表
+// 参考链接：https://developer.work.weixin.qq.com/document/path/90227
+func (a *agentService) List() (result *AgentList, err error) {
+	failCount := -1
+	// 默认尝试一次，即不进行失败重试
+	for failCount < a.client.maxRetryTimes {
+		failCount++
+		var req *http.Request
+		req, err = a.client.newRequest(http.MethodGet, pathAgentList, nil)
+		if err != nil {
+			continue // 如果循环结束，则会返回该 err
+		}
+		result = new(AgentList)
+		err = (*service)(a).doRequest(req, result)
+		if err != nil {
+			continue // 如果循环结束，则会返回该 err
+		}
+		// 成功，return
+		return result, nil
+	}
+	// 失败，返回最后一次请求的 err
+	return nil, err
+}
+
+// 设置应用，仅会修改非空字段
+// ReportLocationFlag、IsReportEnter 为指针，以便能够显式设置为 0
+type AgentSetting struct {
+	AgentID            int    `json:"agentid"`
+	ReportLocationFlag *int   `json:"report_location_flag,omitempty"`
+	LogoMediaID        string `json:"logo_mediaid,omitempty"`
+	Name               string `json:"name,omitempty"`
+	Description        string `json:"description,omitempty"`
+	RedirectDomain     string `json:"redirect_domain,omitempty"`
+	IsReportEnter      *int   `json:"isreportenter,omitempty"`
+	HomeURL            string `json:"home_url,omitempty"`
+}
+
+// SetReportLocation 设置是否打开地理位置上报
+func (s *AgentSetting) SetReportLocation(on bool) *AgentSetting {
+	v := boolToInt(on)
+	s.ReportLocationFlag = &v
+	return s
+}
+
+// SetReportEnter 设置是否上报用户进入应用事件
+func (s *AgentSetting) SetReportEnter(on bool) *AgentSetting {
+	v := boolToInt(on)
+	s.IsReportEnter = &v
+	return s
+}
+
+type AgentResp struct {
+	baseResponse
+}
+
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// 应用管理：设置应用，AgentID 为 0 时使用 NewWithAgentIDOption 设置的应用 ID
+// 参考链接：https://developer.work.weixin.qq.com/document/path/90228
+// LogoMediaID 可通过 Media.Upload 上传图片获得
+func (a *agentService) Set(setting *AgentSetting) (result *AgentResp, err error) {
+	if setting == nil {
+		return nil, errors.New("setting is required")
+	}
+	agentID, err := a.resolveAgentID(setting.AgentID)
+	if err != nil {
+		return nil, err
+	}
+	// 不修改调用方的 setting
+	body := *setting
+	body.AgentID = agentID
+	failCount := -1
+	// 默认尝试一次，即不进行失败重试
+	for failCount < a.client.maxRetryTimes {
+		failCount++
+		var req *http.Request
+		req, err = a.client.newRequest(http.MethodPost, pathAgentSet, body)
+		if err != nil {
+			continue // 如果循环结束，则会返回该 err
+		}
+		result = new(AgentResp)
+		err = (*service)(a).doRequest(req, result)
+		if err != nil {
+			continue // 如果循环结束，则会返回该 err
+		}
+		// 成功，return
+		return result, nil
+	}
+	// 失败，返回最后一次请求的 err
+	return nil, err
+}
@@ -0,0 +1,84 @@
+package wecom
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestAgentGet(t *testing.T) {
+	c, _ := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != pathAgentGet || r.URL.Query().Get("agentid") != "7" {
+			t.Errorf("unexpected request: %s", r.URL)
+		}
+		fmt.Fprint(w, `{"errcode":0,"agentid":7,"name":"app","allow_userinfos":{"user":[{"userid":"a"},{"userid":"b"}]},"allow_partys":{"partyid":[1]}}`)
+	}, NewWithAgentIDOption(7))
+	agent, err := c.Agent.Get(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if agent.Name != "app" || !reflect.DeepEqual(agent.UserIDs(), []string{"a", "b"}) || agent.AllowPartys.PartyID[0] != 1 {
+		t.Fatalf("unexpected agent: %+v", agent)
+	}
+
+	c, _ = newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request: %s", r.URL)
+	})
+	if _, err = c.Agent.Get(0); err == nil {
+		t.Fatal("want error without agent id")
+	}
+}
+
+func TestAgentList(t *testing.T) {
+	c, _ := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != pathAgentList {
+			t.Errorf("unexpected request: %s", r.URL)
+		}
+		fmt.Fprint(w, `{"errcode":0,"agentlist":[{"agentid":1,"name":"a"},{"agentid":2,"name":"b"}]}`)
+	})
+	list, err := c.Agent.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list.AgentList) != 2 || list.AgentList[1].AgentID != 2 {
+		t.Fatalf("unexpected list: %+v", list)
+	}
+}
+
+func TestAgentSet(t *testing.T) {
+	var body map[string]interface{}
+	c, _ := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		body = nil
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Error(err)
+		}
+		fmt.Fprint(w, `{"errcode":0}`)
+	}, NewWithAgentIDOption(7))
+
+	setting := (&AgentSetting{Name: "app"}).SetReportLocation(false).SetReportEnter(false)
+	if _, err := c.Agent.Set(setting); err != nil {
+		t.Fatal(err)
+	}
+	// 显式设置为 0 的开关需要出现在请求中
+	want := map[string]interface{}{"agentid": float64(7), "name": "app", "report_location_flag": float64(0), "isreportenter": float64(0)}
+	if !reflect.DeepEqual(body, want) {
+		t.Fatalf("got body %v, want %v", body, want)
+	}
+	if setting.AgentID != 0 {
+		t.Fatal("caller's setting modified")
+	}
+
+	if _, err := c.Agent.Set(&AgentSetting{AgentID: 8, HomeURL: "https://example.com"}); err != nil {
+		t.Fatal(err)
+	}
+	want = map[string]interface{}{"agentid": float64(8), "home_url": "https://example.com"}
+	if !reflect.DeepEqual(body, want) {
+		t.Fatalf("got body %v, want unset flags omitted", body)
+	}
+
+	if _, err := c.Agent.Set(nil); err == nil {
+		t.Fatal("want error for nil setting")
+	}
+}
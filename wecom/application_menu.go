@@ -0,0 +1,228 @@
+// application_menu.go 自定义菜单
+// 参考链接：https://developer.work.weixin.qq.com/document/path/90230
+package wecom
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+const (
+	pathMenuCreate = "/cgi-bin/menu/create"
+	pathMenuGet    = "/cgi-bin/menu/get"
+	pathMenuDelete = "/cgi-bin/menu/delete"
+)
+
+// 菜单按钮类型
+const (
+	MenuButtonClick           = "click"              // 点击推事件
+	MenuButtonView            = "view"               // 跳转 URL
+	MenuButtonScancodePush    = "scancode_push"      // 扫码推事件
+	MenuButtonScancodeWaitMsg = "scancode_waitmsg"   // 扫码推事件且弹出“消息接收中”提示框
+	MenuButtonPicSysPhoto     = "pic_sysphoto"       // 弹出系统拍照发图
+	MenuButtonPicPhotoOrAlbum = "pic_photo_or_album" // 弹出拍照或者相册发图
+	MenuButtonPicWeixin       = "pic_weixin"         // 弹出企业微信相册发图器
+	MenuButtonLocationSelect  = "location_select"    // 弹出地理位置选择器
+	MenuButtonMiniprogram     = "view_miniprogram"   // 跳转小程序
+)
+
+// 菜单数量及长度限制，长度均为字节数
+const (
+	menuMaxButtons    = 3
+	menuMaxSubButtons = 5
+	menuMaxNameLen    = 16
+	menuMaxSubNameLen = 40
+	menuMaxKeyLen     = 128
+	menuMaxURLLen     = 1024
+)
+
+// 菜单按钮，含 SubButton 的一级菜单不需要填写 Type
+type MenuButton struct {
+	Type      string       `json:"type,omitempty"`
+	Name      string       `json:"name"`
+	Key       string       `json:"key,omitempty"`
+	URL       string       `json:"url,omitempty"`
+	PagePath  string       `json:"pagepath,omitempty"`
+	AppID     string       `json:"appid,omitempty"`
+	SubButton []MenuButton `json:"sub_button,omitempty"`
+}
+
+// 事件类按钮，key 用于在回调事件中识别按钮
+func newEventButton(typ, name, key string) MenuButton {
+	return MenuButton{Type: typ, Name: name, Key: key}
+}
+
+func NewClickButton(name, key string) MenuButton {
+	return newEventButton(MenuButtonClick, name, key)
+}
+
+func NewViewButton(name, url string) MenuButton {
+	return MenuButton{Type: MenuButtonView, Name: name, URL: url}
+}
+
+func NewScancodePushButton(name, key string) MenuButton {
+	return newEventButton(MenuButtonScancodePush, name, key)
+}
+
+func NewScancodeWaitMsgButton(name, key string) MenuButton {
+	return newEventButton(MenuButtonScancodeWaitMsg, name, key)
+}
+
+func NewPicSysPhotoButton(name, key string) MenuButton {
+	return newEventButton(MenuButtonPicSysPhoto, name, key)
+}
+
+func NewPicPhotoOrAlbumButton(name, key string) MenuButton {
+	return newEventButton(MenuButtonPicPhotoOrAlbum, name, key)
+}
+
+func NewPicWeixinButton(name, key string) MenuButton {
+	return newEventButton(MenuButtonPicWeixin, name, key)
+}
+
+func NewLocationSelectButton(name, key string) MenuButton {
+	return newEventButton(MenuButtonLocationSelect, name, key)
+}
+
+// appID 为小程序的 appid，小程序须已关联到应用
+func NewMiniprogramButton(name, appID, pagePath string) MenuButton {
+	return MenuButton{Type: MenuButtonMiniprogram, Name: name, AppID: appID, PagePath: pagePath}
+}
+
+// 二级菜单
+func NewSubMenuButton(name string, subs ...MenuButton) MenuButton {
+	return MenuButton{Name: name, SubButton: subs}
+}
+
+// 应用菜单
+type Menu struct {
+	Button []MenuButton `json:"button"`
+}
+
+func NewMenu(buttons ...MenuButton) *Menu {
+	return &Menu{Button: buttons}
+}
+
+// Add 追加一级菜单
+func (m *Menu) Add(buttons ...MenuButton) *Menu {
+	m.Button = append(m.Button, buttons...)
+	return m
+}
+
+// Validate 检查菜单数量、必填字段及长度限制
+func (m *Menu) Validate() error {
+	if m == nil {
+		return errors.New("menu is required")
+	}
+	if len(m.Button) == 0 || len(m.Button) > menuMaxButtons {
+		return fmt.Errorf("menu: button must have 1 ~ %d items, got %d", menuMaxButtons, len(m.Button))
+	}
+	for i := range m.Button {
+		b := &m.Button[i]
+		if len(b.SubButton) == 0 {
+			if err := b.validate(menuMaxNameLen); err != nil {
+				return fmt.Errorf("menu: button[%d]: %w", i, err)
+			}
+			continue
+		}
+		if len(b.SubButton) > menuMaxSubButtons {
+			return fmt.Errorf("menu: button[%d]: sub_button cannot exceed %d items, got %d", i, menuMaxSubButtons, len(b.SubButton))
+		}
+		if b.Name == "" || len(b.Name) > menuMaxNameLen {
+			return fmt.Errorf("menu: button[%d]: name must be 1 ~ %d bytes", i, menuMaxNameLen)
+		}
+		for j := range b.SubButton {
+			sub := &b.SubButton[j]
+			if len(sub.SubButton) > 0 {
+				return fmt.Errorf("menu: button[%d].sub_button[%d]: only two levels are supported", i, j)
+			}
+			if err := sub.validate(menuMaxSubNameLen); err != nil {
+				return fmt.Errorf("menu: button[%d].sub_button[%d]: %w", i, j, err)
+			}
+		}
+	}
+	return nil
+}
+
+// validate 检查不含二级菜单的按钮
+func (b *MenuButton) validate(maxNameLen int) error {
+	if b.Name == "" || len(b.Name) > maxNameLen {
+		return fmt.Errorf("name must be 1 ~ %d bytes", maxNameLen)
+	}
+	switch b.Type {
+	case MenuButtonClick, MenuButtonScancodePush, MenuButtonScancodeWaitMsg,
+		MenuButtonPicSysPhoto, MenuButtonPicPhotoOrAlbum, MenuButtonPicWeixin, MenuButtonLocationSelect:
+		if b.Key == "" || len(b.Key) > menuMaxKeyLen {
+			return fmt.Errorf("%s: key must be 1 ~ %d bytes", b.Type, menuMaxKeyLen)
+		}
+	case MenuButtonView:
+		if b.URL == "" || len(b.URL) > menuMaxURLLen {
+			return fmt.Errorf("%s: url must be 1 ~ %d bytes", b.Type, menuMaxURLLen)
+		}
+	case MenuButtonMiniprogram:
+		if b.AppID == "" {
+			return errors.New("view_miniprogram: appid is required")
+		}
+		if b.PagePath == "" {
+			return errors.New("view_miniprogram: pagepath is required")
+		}
+	case "":
+		return errors.New("type is required")
+	default:
+		return fmt.Errorf("invalid button type: %s", b.Type)
+	}
+	return nil
+}
+
+type MenuResp struct {
+	baseResponse
+	Button []MenuButton `json:"button"`
+}
+
+// 应用管理：创建菜单，会覆盖应用原有的菜单，agentID 为 0 时使用 NewWithAgentIDOption 设置的应用 ID
+// 参考链接：https://developer.work.weixin.qq.com/document/path/90231
+func (a *agentService) CreateMenu(agentID int, menu *Menu) (result *MenuResp, err error) {
+	if err = menu.Validate(); err != nil {
+		return nil, err
+	}
+	return a.menu(http.MethodPost, pathMenuCreate, agentID, menu)
+}
+
+// 应用管理：获取菜单
+// 参考链接：https://developer.work.weixin.qq.com/document/path/90232
+func (a *agentService) GetMenu(agentID int) (result *MenuResp, err error) {
+	return a.menu(http.MethodGet, pathMenuGet, agentID, nil)
+}
+
+// 应用管理：删除菜单
+// 参考链接：https://developer.work.weixin.qq.com/document/path/90233
+func (a *agentService) DeleteMenu(agentID int) (result *MenuResp, err error) {
+	return a.menu(http.MethodGet, pathMenuDelete, agentID, nil)
+}
+
+func (a *agentService) menu(httpMethod, path string, agentID int, body interface{}) (result *MenuResp, err error) {
+	agentID, err = a.resolveAgentID(agentID)
+	if err != nil {
+		return nil, err
+	}
+	failCount := -1
+	// 默认尝试一次，即不进行失败重试
+	for failCount < a.client.maxRetryTimes {
+		failCount++
+		var req *http.Request
+		req, err = a.client.newRequest(httpMethod, path, body, fmt.Sprintf("agentid=%d", agentID))
+		if err != nil {
+			continue // 如果循环结束，则会返回该 err
+		}
+		result = new(MenuResp)
+		err = (*service)(a).doRequest(req, result)
+		if err != nil {
+			continue // 如果循环结束，则会返回该 err
+		}
+		// 成功，return
+		return result, nil
+	}
+	// 失败，返回最后一次请求的 err
+	return nil, err
+}
@@ -0,0 +1,60 @@
+package wecom
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestMenuValidate(t *testing.T) {
+	click := NewClickButton("a", "k")
+	subs := func(n int) []MenuButton {
+		buttons := make([]MenuButton, n)
+		for i := range buttons {
+			buttons[i] = click
+		}
+		return buttons
+	}
+	tests := []struct {
+		name string
+		menu *Menu
+		ok   bool
+	}{
+		{"nil", nil, false},
+		{"empty", NewMenu(), false},
+		{"3 buttons", NewMenu(subs(3)...), true},
+		{"4 buttons", NewMenu(subs(4)...), false},
+		{"5 sub buttons", NewMenu(NewSubMenuButton("a", subs(5)...)), true},
+		{"6 sub buttons", NewMenu(NewSubMenuButton("a", subs(6)...)), false},
+		{"name 16 bytes", NewMenu(NewClickButton(strings.Repeat("x", 16), "k")), true},
+		{"name 17 bytes", NewMenu(NewClickButton(strings.Repeat("x", 17), "k")), false},
+		// 中文每个字 3 字节
+		{"name 6 chinese characters", NewMenu(NewClickButton(strings.Repeat("菜", 6), "k")), false},
+		{"sub menu name 17 bytes", NewMenu(NewSubMenuButton(strings.Repeat("x", 17), click)), false},
+		{"sub name 40 bytes", NewMenu(NewSubMenuButton("a", NewClickButton(strings.Repeat("x", 40), "k"))), true},
+		{"sub name 41 bytes", NewMenu(NewSubMenuButton("a", NewClickButton(strings.Repeat("x", 41), "k"))), false},
+		{"key 128 bytes", NewMenu(NewClickButton("a", strings.Repeat("k", 128))), true},
+		{"key 129 bytes", NewMenu(NewClickButton("a", strings.Repeat("k", 129))), false},
+		{"empty key", NewMenu(NewClickButton("a", "")), false},
+		{"empty name", NewMenu(NewClickButton("", "k")), false},
+		{"url 1025 bytes", NewMenu(NewViewButton("a", strings.Repeat("u", 1025))), false},
+		{"miniprogram without pagepath", NewMenu(NewMiniprogramButton("a", "wx1", "")), false},
+		{"no type", NewMenu(MenuButton{Name: "a"}), false},
+		{"invalid type", NewMenu(MenuButton{Type: "x", Name: "a"}), false},
+		{"three levels", NewMenu(NewSubMenuButton("a", NewSubMenuButton("b", click))), false},
+	}
+	for _, tt := range tests {
+		if err := tt.menu.Validate(); (err == nil) != tt.ok {
+			t.Errorf("%s: got %v", tt.name, err)
+		}
+	}
+}
+
+func TestCreateMenuNil(t *testing.T) {
+	c, _ := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request: %s", r.URL)
+	}, NewWithAgentIDOption(7))
+	if _, err := c.Agent.CreateMenu(0, nil); err == nil {
+		t.Fatal("want error for nil menu")
+	}
+}
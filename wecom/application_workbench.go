@@ -0,0 +1,338 @@
+// application_workbench.go 工作台自定义展示
+// 参考链接：https://developer.work.weixin.qq.com/document/path/92535
+package wecom
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const (
+	pathWorkbenchTemplateSet = "/cgi-bin/agent/set_workbench_template"
+	pathWorkbenchTemplateGet = "/cgi-bin/agent/get_workbench_template"
+	pathWorkbenchDataSet     = "/cgi-bin/agent/set_workbench_data"
+
+	defaultWorkbenchConcurrency = 4
+	// 未指定 WorkbenchBulkOptions.Rate 时，每秒最多设置的成员数
+	defaultWorkbenchRate = 20
+)
+
+// 工作台展示类型
+const (
+	WorkbenchTypeKeyData = "keydata" // 关键数据型
+	WorkbenchTypeImage   = "image"   // 图片型
+	WorkbenchTypeList    = "list"    // 列表型
+	WorkbenchTypeWebview = "webview" // webview 型
+	WorkbenchTypeNormal  = "normal"  // 普通型，即取消自定义展示
+)
+
+// webview 型的高度
+const (
+	WorkbenchHeightSingleRow = "single_row"
+	WorkbenchHeightDoubleRow = "double_row"
+)
+
+// 关键数据型，最多 4 项
+type WorkbenchKeyData struct {
+	Items []WorkbenchKeyDataItem `json:"items"`
+}
+
+type WorkbenchKeyDataItem struct {
+	Key  string `json:"key,omitempty"`
+	Data string `json:"data"`
+	// 点击跳转的 url，与 PagePath 同时填写时优先跳转小程序
+	JumpURL  string `json:"jump_url,omitempty"`
+	PagePath string `json:"pagepath,omitempty"`
+}
+
+// 图片型
+type WorkbenchImage struct {
+	URL      string `json:"url"`
+	JumpURL  string `json:"jump_url,omitempty"`
+	PagePath string `json:"pagepath,omitempty"`
+}
+
+// 列表型，最多 3 项
+type WorkbenchList struct {
+	Items []WorkbenchListItem `json:"items"`
+}
+
+type WorkbenchListItem struct {
+	Title    string `json:"title"`
+	JumpURL  string `json:"jump_url,omitempty"`
+	PagePath string `json:"pagepath,omitempty"`
+}
+
+// webview 型
+type WorkbenchWebview struct {
+	URL      string `json:"url"`
+	JumpURL  string `json:"jump_url,omitempty"`
+	PagePath string `json:"pagepath,omitempty"`
+	// 高度，single_row 或 double_row，默认为 single_row
+	Height string `json:"height,omitempty"`
+	// 是否隐藏标题
+	HideTitle bool `json:"hide_title,omitempty"`
+}
+
+// 工作台展示内容，仅需填写 Type 对应的字段，建议使用 NewXXXWorkbench 创建
+type WorkbenchContent struct {
+	Type    string            `json:"type"`
+	KeyData *WorkbenchKeyData `json:"keydata,omitempty"`
+	Image   *WorkbenchImage   `json:"image,omitempty"`
+	List    *WorkbenchList    `json:"list,omitempty"`
+	Webview *WorkbenchWebview `json:"webview,omitempty"`
+}
+
+func NewKeyDataWorkbench(items ...WorkbenchKeyDataItem) *WorkbenchContent {
+	return &WorkbenchContent{Type: WorkbenchTypeKeyData, KeyData: &WorkbenchKeyData{Items: items}}
+}
+
+func NewImageWorkbench(url, jumpURL string) *WorkbenchContent {
+	return &WorkbenchContent{Type: WorkbenchTypeImage, Image: &WorkbenchImage{URL: url, JumpURL: jumpURL}}
+}
+
+func NewListWorkbench(items ...WorkbenchListItem) *WorkbenchContent {
+	return &WorkbenchContent{Type: WorkbenchTypeList, List: &WorkbenchList{Items: items}}
+}
+
+func NewWebviewWorkbench(url, height string) *WorkbenchContent {
+	return &WorkbenchContent{Type: WorkbenchTypeWebview, Webview: &WorkbenchWebview{URL: url, Height: height}}
+}
+
+func NewNormalWorkbench() *WorkbenchContent {
+	return &WorkbenchContent{Type: WorkbenchTypeNormal}
+}
+
+// Validate 检查 Type 对应的字段及数量限制
+func (w *WorkbenchContent) Validate() error {
+	if w == nil {
+		return errors.New("workbench content is required")
+	}
+	switch w.Type {
+	case WorkbenchTypeKeyData:
+		if w.KeyData == nil || len(w.KeyData.Items) == 0 || len(w.KeyData.Items) > 4 {
+			return errors.New("keydata: items must have 1 ~ 4 items")
+		}
+	case WorkbenchTypeImage:
+		if w.Image == nil || w.Image.URL == "" {
+			return errors.New("image: url is required")
+		}
+	case WorkbenchTypeList:
+		if w.List == nil || len(w.List.Items) == 0 || len(w.List.Items) > 3 {
+			return errors.New("list: items must have 1 ~ 3 items")
+		}
+	case WorkbenchTypeWebview:
+		if w.Webview == nil || w.Webview.URL == "" {
+			return errors.New("webview: url is required")
+		}
+		if h := w.Webview.Height; h != "" && h != WorkbenchHeightSingleRow && h != WorkbenchHeightDoubleRow {
+			return fmt.Errorf("webview: invalid height: %s", h)
+		}
+	case WorkbenchTypeNormal:
+	default:
+		return fmt.Errorf("invalid workbench type: %s", w.Type)
+	}
+	return nil
+}
+
+// 工作台模板，对应用可见范围内的全部成员生效
+type WorkbenchTemplate struct {
+	WorkbenchContent
+	// 是否覆盖已通过 set_workbench_data 为成员设置的数据
+	ReplaceUserData bool `json:"replace_user_data,omitempty"`
+}
+
+type workbenchTemplateReq struct {
+	AgentID int `json:"agentid"`
+	*WorkbenchTemplate
+}
+
+type workbenchDataReq struct {
+	AgentID int    `json:"agentid"`
+	Userid  string `json:"userid"`
+	*WorkbenchContent
+}
+
+type WorkbenchResp struct {
+	baseResponse
+}
+
+type WorkbenchTemplateResp struct {
+	baseResponse
+	WorkbenchTemplate
+}
+
+// 应用管理：设置应用在工作台展示的模板，agentID 为 0 时使用 NewWithAgentIDOption 设置的应用 ID
+// 参考链接：https://developer.work.weixin.qq.com/document/path/92535
+func (a *agentService) SetWorkbenchTemplate(agentID int, tpl *WorkbenchTemplate) (result *WorkbenchResp, err error) {
+	if tpl == nil {
+		return nil, errors.New("workbench template is required")
+	}
+	if err = tpl.Validate(); err != nil {
+		return nil, err
+	}
+	agentID, err = a.resolveAgentID(agentID)
+	if err != nil {
+		return nil, err
+	}
+	failCount := -1
+	// 默认尝试一次，即不进行失败重试
+	for failCount < a.client.maxRetryTimes {
+		failCount++
+		body := workbenchTemplateReq{AgentID: agentID, WorkbenchTemplate: tpl}
+		var req *http.Request
+		req, err = a.client.newRequest(http.MethodPost, pathWorkbenchTemplateSet, body)
+		if err != nil {
+			continue // 如果循环结束，则会返回该 err
+		}
+		result = new(WorkbenchResp)
+		err = (*service)(a).doRequest(req, result)
+		if err != nil {
+			continue // 如果循环结束，则会返回该 err
+		}
+		// 成功，return
+		return result, nil
+	}
+	// 失败，返回最后一次请求的 err
+	return nil, err
+}
+
+// 应用管理：获取应用在工作台展示的模板
+// 参考链接：https://developer.work.weixin.qq.com/document/path/92535
+func (a *agentService) GetWorkbenchTemplate(agentID int) (result *WorkbenchTemplateResp, err error) {
+	agentID, err = a.resolveAgentID(agentID)
+	if err != nil {
+		return nil, err
+	}
+	failCount := -1
+	// 默认尝试一次，即不进行失败重试
+	for failCount < a.client.maxRetryTimes {
+		failCount++
+		body := struct {
+			AgentID int `json:"agentid"`
+		}{AgentID: agentID}
+		var req *http.Request
+		req, err = a.client.newRequest(http.MethodPost, pathWorkbenchTemplateGet, body)
+		if err != nil {
+			continue // 如果循环结束，则会返回该 err
+		}
+		result = new(WorkbenchTemplateResp)
+		err = (*service)(a).doRequest(req, result)
+		if err != nil {
+			continue // 如果循环结束，则会返回该 err
+		}
+		// 成功，return
+		return result, nil
+	}
+	// 失败，返回最后一次请求的 err
+	return nil, err
+}
+
+// 应用管理：设置应用在指定成员工作台展示的数据，Type 需与模板一致
+// 参考链接：https://developer.work.weixin.qq.com/document/path/92535
+func (a *agentService) SetWorkbenchData(agentID int, userID string, data *WorkbenchContent) (result *WorkbenchResp, err error) {
+	if userID == "" {
+		return nil, errors.New("userid is required")
+	}
+	if err = data.Validate(); err != nil {
+		return nil, err
+	}
+	agentID, err = a.resolveAgentID(agentID)
+	if err != nil {
+		return nil, err
+	}
+	failCount := -1
+	// 默认尝试一次，即不进行失败重试
+	for failCount < a.client.maxRetryTimes {
+		failCount++
+		body := workbenchDataReq{AgentID: agentID, Userid: userID, WorkbenchContent: data}
+		var req *http.Request
+		req, err = a.client.newRequest(http.MethodPost, pathWorkbenchDataSet, body)
+		if err != nil {
+			continue // 如果循环结束，则会返回该 err
+		}
+		result = new(WorkbenchResp)
+		err = (*service)(a).doRequest(req, result)
+		if err != nil {
+			continue // 如果循环结束，则会返回该 err
+		}
+		// 成功，return
+		return result, nil
+	}
+	// 失败，返回最后一次请求的 err
+	return nil, err
+}
+
+// WorkbenchBulkOptions 批量设置成员工作台数据的选项，零值均使用默认值
+type WorkbenchBulkOptions struct {
+	// 并发数
+	Concurrency int
+	// 每秒最多设置的成员数，在 Client 限流器之外额外生效
+	Rate int
+}
+
+// WorkbenchBulkReport 批量设置的结果
+type WorkbenchBulkReport struct {
+	Total     int
+	Succeeded int
+	Failures  []WorkbenchFailure
+}
+
+// WorkbenchFailure 设置失败的成员
+type WorkbenchFailure struct {
+	Userid string
+	Err    error
+}
+
+// WorkbenchUserData 单个成员的工作台数据
+type WorkbenchUserData struct {
+	Userid string
+	Data   *WorkbenchContent
+}
+
+// SetWorkbenchDataBulk 应用管理：批量设置成员工作台展示的数据
+// 触发频率限制（45009）时按指数退避重试；任一成员失败时按 data 的顺序返回第一个错误，report 中包含全部成员的结果
+func (a *agentService) SetWorkbenchDataBulk(ctx context.Context, agentID int, data []WorkbenchUserData, opts WorkbenchBulkOptions) (report *WorkbenchBulkReport, err error) {
+	agentID, err = a.resolveAgentID(agentID)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = defaultWorkbenchConcurrency
+	}
+	if opts.Rate <= 0 {
+		opts.Rate = defaultWorkbenchRate
+	}
+	s := a.WithContext(ctx)
+	limiter := newRateLimiter(opts.Rate, time.Second)
+
+	errs := parallel(ctx, len(data), opts.Concurrency, func(k int) error {
+		return retryOnFreqLimit(ctx, func() error {
+			// 重试同样计入 opts.Rate
+			if err := limiter.wait(ctx); err != nil {
+				return err
+			}
+			result, err := s.SetWorkbenchData(agentID, data[k].Userid, data[k].Data)
+			if err == nil {
+				err = checkResponse(result)
+			}
+			return err
+		})
+	})
+
+	report = &WorkbenchBulkReport{Total: len(data)}
+	for k := range data {
+		if errs[k] == nil {
+			report.Succeeded++
+			continue
+		}
+		report.Failures = append(report.Failures, WorkbenchFailure{Userid: data[k].Userid, Err: errs[k]})
+		if err == nil {
+			err = fmt.Errorf("userid %s: %w", data[k].Userid, errs[k])
+		}
+	}
+	return report, err
+}
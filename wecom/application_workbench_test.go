@@ -0,0 +1,100 @@
+package wecom
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSetWorkbenchDataBulk(t *testing.T) {
+	defer func(d time.Duration) { freqLimitBackoff = d }(freqLimitBackoff)
+	freqLimitBackoff = time.Millisecond
+
+	mu := &sync.Mutex{}
+	limited := make(map[string]bool)
+	c, _ := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		var body workbenchDataReq
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case strings.HasPrefix(body.Userid, "bad"):
+			fmt.Fprint(w, `{"errcode":60111,"errmsg":"userid not found"}`)
+		case !limited[body.Userid]:
+			// 每个成员第一次请求都触发频率限制
+			limited[body.Userid] = true
+			fmt.Fprint(w, `{"errcode":45009,"errmsg":"api freq out of limit"}`)
+		default:
+			fmt.Fprint(w, `{"errcode":0}`)
+		}
+	}, NewWithAgentIDOption(1))
+
+	var data []WorkbenchUserData
+	for i := 0; i < 20; i++ {
+		data = append(data, WorkbenchUserData{Userid: fmt.Sprint("u", i), Data: NewImageWorkbench("http://img", "")})
+	}
+	data = append(data, WorkbenchUserData{Userid: "bad1", Data: NewImageWorkbench("http://img", "")})
+	data = append(data, WorkbenchUserData{Userid: "bad2", Data: NewImageWorkbench("http://img", "")})
+
+	report, err := c.Agent.SetWorkbenchDataBulk(context.Background(), 0, data, WorkbenchBulkOptions{Concurrency: 4, Rate: 1000})
+	if err == nil || !strings.HasPrefix(err.Error(), "userid bad1:") {
+		t.Fatalf("got err %v, want the error of bad1", err)
+	}
+	if report.Total != 22 || report.Succeeded != 20 || len(report.Failures) != 2 || report.Failures[1].Userid != "bad2" {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+}
+
+func TestSetWorkbenchDataBulkRetriesCountAgainstRate(t *testing.T) {
+	defer func(d time.Duration) { freqLimitBackoff = d }(freqLimitBackoff)
+	freqLimitBackoff = time.Millisecond
+
+	mu := &sync.Mutex{}
+	limited := make(map[string]bool)
+	c, _ := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		var body workbenchDataReq
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		mu.Lock()
+		defer mu.Unlock()
+		if !limited[body.Userid] {
+			limited[body.Userid] = true
+			fmt.Fprint(w, `{"errcode":45009,"errmsg":"api freq out of limit"}`)
+			return
+		}
+		fmt.Fprint(w, `{"errcode":0}`)
+	}, NewWithAgentIDOption(1))
+
+	data := []WorkbenchUserData{
+		{Userid: "a", Data: NewNormalWorkbench()},
+		{Userid: "b", Data: NewNormalWorkbench()},
+	}
+	start := time.Now()
+	// 每秒 2 次，4 次请求中的后 2 次需要等待令牌
+	if _, err := c.Agent.SetWorkbenchDataBulk(context.Background(), 0, data, WorkbenchBulkOptions{Concurrency: 2, Rate: 2}); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 800*time.Millisecond {
+		t.Fatalf("finished in %v, retries bypassed the rate limit", elapsed)
+	}
+}
+
+func TestSetWorkbenchNil(t *testing.T) {
+	c, _ := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request: %s", r.URL)
+	}, NewWithAgentIDOption(1))
+	if _, err := c.Agent.SetWorkbenchTemplate(0, nil); err == nil {
+		t.Fatal("want error for nil template")
+	}
+	if _, err := c.Agent.SetWorkbenchData(0, "u", nil); err == nil {
+		t.Fatal("want error for nil data")
+	}
+	report, err := c.Agent.SetWorkbenchDataBulk(context.Background(), 0, []WorkbenchUserData{{Userid: "u"}}, WorkbenchBulkOptions{})
+	if err == nil || len(report.Failures) != 1 {
+		t.Fatalf("got %+v, %v; want a failure for nil data", report, err)
+	}
+}
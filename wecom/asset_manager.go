@@ -1,3 +1,247 @@
-// basic.go 对应的是 https://work.weixin.qq.com/api/doc/90000/90135/91054 文档内容
-// basic.go 对应的是  文档内容
+// asset_manager.go 对应的是 https://work.weixin.qq.com/api/doc/90000/90135/91054 文档内容
+// 主要实现了素材管理的上传、下载 API
 package wecom
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	pathMediaUpload    = "/cgi-bin/media/upload"
+	pathMediaUploadImg = "/cgi-bin/media/uploadimg"
+	pathMediaGet       = "/cgi-bin/media/get"
+	pathMediaGetJSSDK  = "/cgi-bin/media/get/jssdk"
+
+	// 所有文件 size 必须大于 5 个字节
+	mediaMinSize = 5
+	// 永久图片不超过 2MB
+	mediaMaxImgSize = 2 << 20
+)
+
+// 媒体文件类型
+type MediaType string
+
+const (
+	MediaTypeImage MediaType = "image"
+	MediaTypeVoice MediaType = "voice"
+	MediaTypeVideo MediaType = "video"
+	MediaTypeFile  MediaType = "file"
+)
+
+// 各类型媒体文件的大小上限及支持的格式，格式为空表示不限制
+var mediaLimits = map[MediaType]struct {
+	maxSize int64
+	formats []string
+}{
+	MediaTypeImage: {10 << 20, []string{".jpg", ".jpeg", ".png"}},
+	MediaTypeVoice: {2 << 20, []string{".amr"}},
+	MediaTypeVideo: {10 << 20, []string{".mp4"}},
+	MediaTypeFile:  {20 << 20, nil},
+}
+
+type mediaService service
+
+func (m *mediaService) WithContext(ctx context.Context) *mediaService {
+	return &mediaService{
+		client: m.client,
+		ctx:    ctx,
+	}
+}
+
+// checkMedia 校验媒体文件的格式和大小，size 小于 0 表示大小未知，只校验格式
+func checkMedia(mediaType MediaType, filename string, size int64) error {
+	limit, ok := mediaLimits[mediaType]
+	if !ok {
+		return fmt.Errorf("invalid media type: %s", mediaType)
+	}
+	// 大小未知时由 NewMultipartFileFromReader 在读取时检查上限
+	if size >= 0 && (size < mediaMinSize || size > limit.maxSize) {
+		return fmt.Errorf("%s size must be between %d and %d bytes, got %d", mediaType, mediaMinSize, limit.maxSize, size)
+	}
+	if len(limit.formats) == 0 {
+		return nil
+	}
+	ext := strings.ToLower(filepath.Ext(filename))
+	for _, f := range limit.formats {
+		if ext == f {
+			return nil
+		}
+	}
+	return fmt.Errorf("%s format must be one of %v, got %q", mediaType, limit.formats, ext)
+}
+
+type MediaResp struct {
+	baseResponse
+	Type      string `json:"type,omitempty"`
+	MediaID   string `json:"media_id,omitempty"`
+	CreatedAt string `json:"created_at,omitempty"`
+	// 仅上传图片接口返回
+	URL string `json:"url,omitempty"`
+}
+
+// Upload 素材管理：上传临时素材，media_id 三天内有效
+// 参考链接：https://developer.work.weixin.qq.com/document/path/90253
+// 图片 10MB，支持 jpg、png；语音 2MB，支持 amr；视频 10MB，支持 mp4；普通文件 20MB
+// r 同时实现了 io.ReaderAt 和 io.Seeker（如 *os.File、*bytes.Reader）时可以失败重试，
+// 否则 r 只会被读取一次，无法失败重试，且文件大小只在读取时检查，参考 NewMultipartFileFromReader
+func (m *mediaService) Upload(mediaType MediaType, filename string, r io.Reader) (result *MediaResp, err error) {
+	limit, ok := mediaLimits[mediaType]
+	if !ok {
+		return nil, fmt.Errorf("invalid media type: %s", mediaType)
+	}
+	file, err := NewMultipartFileFromReader(filename, r, limit.maxSize)
+	if err != nil {
+		return nil, err
+	}
+	return m.UploadFile(mediaType, file)
+}
+
+// UploadFile 素材管理：上传临时素材，与 Upload 相同，文件通过 MultipartFile 提供
+func (m *mediaService) UploadFile(mediaType MediaType, file *MultipartFile) (result *MediaResp, err error) {
+	if err = checkMedia(mediaType, file.Filename, file.Size); err != nil {
+		return nil, err
+	}
+
+	failCount := -1
+	// 默认尝试一次，即不进行失败重试
+	for failCount < m.client.maxRetryTimes {
+		failCount++
+		var req *http.Request
+		req, err = m.client.newRequest(http.MethodPost, pathMediaUpload, file, "type="+string(mediaType))
+		if err != nil {
+			continue // 如果循环结束，则会返回该 err
+		}
+		result = new(MediaResp)
+		err = (*service)(m).doRequest(req, result)
+		if err != nil {
+			continue // 如果循环结束，则会返回该 err
+		}
+		// 成功，return
+		return result, nil
+	}
+	// 失败，返回最后一次请求的 err
+	return nil, err
+}
+
+// UploadImg 素材管理：上传图片，返回永久有效的图片 URL，仅可用于图文消息正文等企业微信内的场景
+// 参考链接：https://developer.work.weixin.qq.com/document/path/90256
+// 图片文件大小应在 5B ~ 2MB 之间，支持 jpg、png
+func (m *mediaService) UploadImg(filename string, r io.Reader) (result *MediaResp, err error) {
+	file, err := NewMultipartFileFromReader(filename, r, mediaMaxImgSize)
+	if err != nil {
+		return nil, err
+	}
+	if err = checkMedia(MediaTypeImage, filename, file.Size); err != nil {
+		return nil, err
+	}
+
+	failCount := -1
+	// 默认尝试一次，即不进行失败重试
+	for failCount < m.client.maxRetryTimes {
+		failCount++
+		var req *http.Request
+		req, err = m.client.newRequest(http.MethodPost, pathMediaUploadImg, file)
+		if err != nil {
+			continue // 如果循环结束，则会返回该 err
+		}
+		result = new(MediaResp)
+		err = (*service)(m).doRequest(req, result)
+		if err != nil {
+			continue // 如果循环结束，则会返回该 err
+		}
+		// 成功，return
+		return result, nil
+	}
+	// 失败，返回最后一次请求的 err
+	return nil, err
+}
+
+// MediaFile 下载的文件信息，来自响应头
+type MediaFile struct {
+	ContentType string
+	// 来自 Content-Disposition，可能为空
+	Filename string
+	// 响应的 Content-Length，未知时为 -1
+	ContentLength int64
+	// 分段下载时的 Content-Range
+	ContentRange string
+	// 实际写入的字节数
+	Written int64
+}
+
+// Get 素材管理：获取临时素材，将文件内容写入 w
+// 参考链接：https://developer.work.weixin.qq.com/document/path/90254
+func (m *mediaService) Get(mediaID string, w io.Writer) (file *MediaFile, err error) {
+	return m.GetRange(mediaID, w, 0, -1)
+}
+
+// GetRange 素材管理：分段获取临时素材，适用于大文件断点续传
+// offset 为起始字节，length 为读取的字节数，length 小于等于 0 表示读取到文件末尾
+func (m *mediaService) GetRange(mediaID string, w io.Writer, offset, length int64) (file *MediaFile, err error) {
+	if offset < 0 {
+		return nil, fmt.Errorf("invalid offset: %d", offset)
+	}
+	req, err := m.client.newRequest(http.MethodGet, pathMediaGet, nil, "media_id="+mediaID)
+	if err != nil {
+		return nil, err
+	}
+	if rangeHeader := byteRange(offset, length); rangeHeader != "" {
+		req.Header.Set("Range", rangeHeader)
+	}
+	return m.download(req, w)
+}
+
+// byteRange 构造 Range 请求头，length 小于等于 0 表示到文件末尾，读取整个文件时返回空字符串
+func byteRange(offset, length int64) string {
+	if length <= 0 {
+		if offset == 0 {
+			return ""
+		}
+		return fmt.Sprintf("bytes=%d-", offset)
+	}
+	return fmt.Sprintf("bytes=%d-%d", offset, offset+length-1)
+}
+
+// GetJSSDK 素材管理：获取高清语音素材，即通过 JSSDK 的 uploadVoice 上传的语音，格式为 speex，16K 采样率
+// 参考链接：https://developer.work.weixin.qq.com/document/path/90255
+func (m *mediaService) GetJSSDK(mediaID string, w io.Writer) (file *MediaFile, err error) {
+	req, err := m.client.newRequest(http.MethodGet, pathMediaGetJSSDK, nil, "media_id="+mediaID)
+	if err != nil {
+		return nil, err
+	}
+	return m.download(req, w)
+}
+
+// GetReader 素材管理：获取临时素材，返回文件内容的 io.ReadCloser，调用方需要负责关闭
+// 参考链接：https://developer.work.weixin.qq.com/document/path/90254
+func (m *mediaService) GetReader(mediaID string) (stream *StreamResponse, err error) {
+	req, err := m.client.newRequest(http.MethodGet, pathMediaGet, nil, "media_id="+mediaID)
+	if err != nil {
+		return nil, err
+	}
+	return (*service)(m).doStreamRequest(req)
+}
+
+// download 下载文件并写入 w
+// 由于文件内容可能已部分写入 w，下载不进行失败重试
+func (m *mediaService) download(req *http.Request, w io.Writer) (*MediaFile, error) {
+	stream, err := (*service)(m).doStreamRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	defer stream.Body.Close()
+
+	file := &MediaFile{
+		ContentType:   stream.ContentType,
+		Filename:      stream.Filename,
+		ContentLength: stream.ContentLength,
+		ContentRange:  stream.Header.Get("Content-Range"),
+	}
+	file.Written, err = io.Copy(w, stream.Body)
+	return file, err
+}
@@ -0,0 +1,293 @@
+// asset_manager_async.go 异步上传临时素材，适用于超过 20MB 的视频、文件
+// 参考链接：https://developer.work.weixin.qq.com/document/path/96219
+package wecom
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+const (
+	pathMediaUploadByURL       = "/cgi-bin/media/upload_by_url"
+	pathMediaUploadByURLResult = "/cgi-bin/media/get_upload_by_url_result"
+
+	// 异步上传的文件最大 200MB
+	mediaMaxAsyncSize = 200 << 20
+	// 使用场景，1 表示客户联系入群欢迎语素材，目前仅支持该场景
+	UploadSceneWelcome = 1
+
+	uploadJobMinPoll = 2 * time.Second
+	uploadJobMaxPoll = 10 * time.Second
+)
+
+// 异步上传任务状态
+const (
+	UploadJobProcessing = 1 // 处理中
+	UploadJobSucceeded  = 2 // 完成
+	UploadJobFailed     = 3 // 异常失败
+)
+
+// UploadByURLRequest 异步上传请求，Type 仅支持 video、file
+type UploadByURLRequest struct {
+	Scene    int       `json:"scene"`
+	Type     MediaType `json:"type"`
+	Filename string    `json:"filename"`
+	// 文件的 URL，需支持 Range 分块下载
+	URL string `json:"url"`
+	// 文件的 md5，可通过 FileMD5 计算
+	MD5 string `json:"md5"`
+}
+
+type UploadByURLResp struct {
+	baseResponse
+	JobID string `json:"jobid"`
+}
+
+type uploadJobQuery struct {
+	JobID string `json:"jobid"`
+}
+
+type UploadByURLResult struct {
+	baseResponse
+	Status int `json:"status"`
+	Detail struct {
+		ErrCode   int    `json:"errcode"`
+		ErrMsg    string `json:"errmsg"`
+		MediaID   string `json:"media_id"`
+		CreatedAt string `json:"created_at"`
+	} `json:"detail"`
+}
+
+// FileMD5 计算 r 的 md5，返回十六进制字符串
+func FileMD5(r io.Reader) (string, error) {
+	h := md5.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// UploadByURL 素材管理：生成异步上传任务，Scene 为 0 时使用 UploadSceneWelcome
+// 得到的 media_id 仅可用于客户联系的入群欢迎语
+// 参考链接：https://developer.work.weixin.qq.com/document/path/96219
+func (m *mediaService) UploadByURL(upload *UploadByURLRequest) (result *UploadByURLResp, err error) {
+	if upload.Type != MediaTypeVideo && upload.Type != MediaTypeFile {
+		return nil, fmt.Errorf("media type %s is not supported by upload_by_url", upload.Type)
+	}
+	if upload.MD5 == "" {
+		return nil, errors.New("md5 is required")
+	}
+	if upload.Scene == 0 {
+		c := *upload
+		c.Scene = UploadSceneWelcome
+		upload = &c
+	}
+
+	failCount := -1
+	// 默认尝试一次，即不进行失败重试
+	for failCount < m.client.maxRetryTimes {
+		failCount++
+		var req *http.Request
+		req, err = m.client.newRequest(http.MethodPost, pathMediaUploadByURL, upload)
+		if err != nil {
+			continue // 如果循环结束，则会返回该 err
+		}
+		result = new(UploadByURLResp)
+		err = (*service)(m).doRequest(req, result)
+		if err != nil {
+			continue // 如果循环结束，则会返回该 err
+		}
+		// 成功，return
+		return result, nil
+	}
+	// 失败，返回最后一次请求的 err
+	return nil, err
+}
+
+// GetUploadByURLResult 素材管理：查询异步任务结果
+// 参考链接：https://developer.work.weixin.qq.com/document/path/96219
+func (m *mediaService) GetUploadByURLResult(jobID string) (result *UploadByURLResult, err error) {
+	failCount := -1
+	// 默认尝试一次，即不进行失败重试
+	for failCount < m.client.maxRetryTimes {
+		failCount++
+		body := uploadJobQuery{JobID: jobID}
+		var req *http.Request
+		req, err = m.client.newRequest(http.MethodPost, pathMediaUploadByURLResult, body)
+		if err != nil {
+			continue // 如果循环结束，则会返回该 err
+		}
+		result = new(UploadByURLResult)
+		err = (*service)(m).doRequest(req, result)
+		if err != nil {
+			continue // 如果循环结束，则会返回该 err
+		}
+		// 成功，return
+		return result, nil
+	}
+	// 失败，返回最后一次请求的 err
+	return nil, err
+}
+
+// UploadJob 异步上传任务
+type UploadJob struct {
+	JobID   string
+	service *mediaService
+}
+
+// StartUploadByURL 生成异步上传任务，通过 Wait 等待任务完成
+func (m *mediaService) StartUploadByURL(upload *UploadByURLRequest) (*UploadJob, error) {
+	result, err := m.UploadByURL(upload)
+	if err != nil {
+		return nil, err
+	}
+	if err = checkResponse(result); err != nil {
+		return nil, err
+	}
+	return &UploadJob{JobID: result.JobID, service: &mediaService{client: m.client}}, nil
+}
+
+// Wait 轮询任务结果直到完成、失败或 ctx 被取消，成功时返回 media_id
+func (j *UploadJob) Wait(ctx context.Context) (mediaID string, err error) {
+	s := j.service.WithContext(ctx)
+	interval := uploadJobMinPoll
+	for {
+		result, err := s.GetUploadByURLResult(j.JobID)
+		if err != nil {
+			return "", err
+		}
+		if err = checkResponse(result); err != nil {
+			return "", err
+		}
+		switch result.Status {
+		case UploadJobSucceeded:
+			return result.Detail.MediaID, nil
+		case UploadJobFailed:
+			return "", &Error{ErrCode: result.Detail.ErrCode, ErrMsg: result.Detail.ErrMsg}
+		}
+
+		if err = sleepContext(ctx, interval); err != nil {
+			return "", err
+		}
+		if interval *= 2; interval > uploadJobMaxPoll {
+			interval = uploadJobMaxPoll
+		}
+	}
+}
+
+// MediaSource 待上传的素材
+type MediaSource struct {
+	Filename string
+	// 文件内容，不超过同步上传的大小上限时使用同步上传
+	Reader io.Reader
+	// 文件大小，为 0 时通过 io.Seeker 获取，Reader 不支持 Seek 时先读取不超过同步上传上限的部分来判断
+	Size int64
+	// 文件的公网 URL，超过同步上传的大小上限且设置了 Scene 时使用异步上传
+	URL string
+	// 文件的 md5，异步上传时为空则通过 Reader 计算
+	MD5 string
+	// 异步上传的使用场景，为 0 时不使用异步上传，目前仅支持 UploadSceneWelcome
+	Scene int
+}
+
+// UploadAuto 素材管理：上传临时素材，不超过同步上传的大小上限时使用同步上传，返回 media_id
+// 超过上限时，仅当 src.Scene 不为 0 时才使用异步上传，否则返回错误
+// 注意：异步上传得到的 media_id 只能用于 Scene 对应的场景，即客户联系的入群欢迎语，不能用于发送应用消息等
+// 异步上传仅支持 video、file，需要提供 URL
+func (m *mediaService) UploadAuto(ctx context.Context, mediaType MediaType, src *MediaSource) (mediaID string, err error) {
+	limit, ok := mediaLimits[mediaType]
+	if !ok {
+		return "", fmt.Errorf("invalid media type: %s", mediaType)
+	}
+	reader, size := src.Reader, src.Size
+	if size == 0 && reader != nil {
+		if size, err = readerSize(reader); err != nil {
+			return "", err
+		}
+	}
+	// 无法获取大小时先读取不超过同步上传上限的部分，据此选择上传方式
+	sizeKnown := size > 0
+	if !sizeKnown && reader != nil {
+		head, err := ioutil.ReadAll(io.LimitReader(reader, limit.maxSize+1))
+		if err != nil {
+			return "", err
+		}
+		size = int64(len(head))
+		if size <= limit.maxSize {
+			reader, sizeKnown = bytes.NewReader(head), true
+		} else {
+			reader = io.MultiReader(bytes.NewReader(head), reader)
+		}
+	}
+
+	s := m.WithContext(ctx)
+	if reader != nil && size <= limit.maxSize {
+		result, err := s.Upload(mediaType, src.Filename, reader)
+		if err != nil {
+			return "", err
+		}
+		if err = checkResponse(result); err != nil {
+			return "", err
+		}
+		return result.MediaID, nil
+	}
+
+	if src.Scene == 0 {
+		return "", fmt.Errorf("%s size must not exceed %d bytes, set scene to upload asynchronously", mediaType, limit.maxSize)
+	}
+	if src.URL == "" {
+		return "", fmt.Errorf("%s larger than %d bytes requires url", mediaType, limit.maxSize)
+	}
+	if sizeKnown && size > mediaMaxAsyncSize {
+		return "", fmt.Errorf("%s size must not exceed %d bytes, got %d", mediaType, mediaMaxAsyncSize, size)
+	}
+	sum := src.MD5
+	if sum == "" {
+		if reader == nil {
+			return "", errors.New("md5 or reader is required")
+		}
+		// 大小未知时在计算 md5 的同时统计大小
+		h := md5.New()
+		n, err := io.Copy(h, reader)
+		if err != nil {
+			return "", err
+		}
+		if n > mediaMaxAsyncSize {
+			return "", fmt.Errorf("%s size must not exceed %d bytes, got %d", mediaType, mediaMaxAsyncSize, n)
+		}
+		sum = hex.EncodeToString(h.Sum(nil))
+	}
+	job, err := s.StartUploadByURL(&UploadByURLRequest{Scene: src.Scene, Type: mediaType, Filename: src.Filename, URL: src.URL, MD5: sum})
+	if err != nil {
+		return "", err
+	}
+	return job.Wait(ctx)
+}
+
+// readerSize 获取 io.Seeker 剩余的字节数，并恢复读取位置；不支持 Seek 时返回 0
+func readerSize(r io.Reader) (int64, error) {
+	seeker, ok := r.(io.Seeker)
+	if !ok {
+		return 0, nil
+	}
+	cur, err := seeker.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return 0, err
+	}
+	end, err := seeker.Seek(0, io.SeekEnd)
+	if err != nil {
+		return 0, err
+	}
+	if _, err = seeker.Seek(cur, io.SeekStart); err != nil {
+		return 0, err
+	}
+	return end - cur, nil
+}
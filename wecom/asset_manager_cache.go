@@ -0,0 +1,192 @@
+// asset_manager_cache.go 临时素材缓存
+// 相同内容的文件只上传一次，media_id 过期（三天）后自动重新上传
+package wecom
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// 临时素材有效期为三天，预留一小时的余量，避免发送时刚好过期
+const defaultMediaCacheTTL = 3*24*time.Hour - time.Hour
+
+// MediaCacheEntry 缓存的 media_id
+type MediaCacheEntry struct {
+	MediaID   string    `json:"media_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// MediaCacheStore 缓存存储，多实例部署时可基于 Redis 等实现以共享缓存，实现需要保证并发安全
+type MediaCacheStore interface {
+	// Get 读取缓存，不存在时 ok 为 false
+	Get(key string) (entry *MediaCacheEntry, ok bool, err error)
+	Set(key string, entry *MediaCacheEntry) error
+	Delete(key string) error
+}
+
+// MemoryMediaCacheStore 基于内存的 MediaCacheStore
+type MemoryMediaCacheStore struct {
+	mu      sync.RWMutex
+	entries map[string]MediaCacheEntry
+}
+
+func NewMemoryMediaCacheStore() *MemoryMediaCacheStore {
+	return &MemoryMediaCacheStore{entries: make(map[string]MediaCacheEntry)}
+}
+
+func (s *MemoryMediaCacheStore) Get(key string) (*MediaCacheEntry, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	entry, ok := s.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+	return &entry, true, nil
+}
+
+func (s *MemoryMediaCacheStore) Set(key string, entry *MediaCacheEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries[key] = *entry
+	return nil
+}
+
+func (s *MemoryMediaCacheStore) Delete(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.entries, key)
+	return nil
+}
+
+// MediaCache 以文件内容的哈希为 key 缓存 media_id
+type MediaCache struct {
+	service *mediaService
+	store   MediaCacheStore
+	// media_id 的有效期，默认为三天减一小时
+	TTL time.Duration
+
+	mu sync.Mutex
+	// 同一文件并发上传时只上传一次
+	inflight map[string]*mediaUploadCall
+}
+
+type mediaUploadCall struct {
+	done    chan struct{}
+	mediaID string
+	err     error
+	// 因上传的调用方 ctx 被取消而失败
+	canceled bool
+}
+
+// NewMediaCache 创建素材缓存，store 为 nil 时使用 MemoryMediaCacheStore
+func (m *mediaService) NewMediaCache(store MediaCacheStore) *MediaCache {
+	if store == nil {
+		store = NewMemoryMediaCacheStore()
+	}
+	return &MediaCache{
+		service:  &mediaService{client: m.client},
+		store:    store,
+		TTL:      defaultMediaCacheTTL,
+		inflight: make(map[string]*mediaUploadCall),
+	}
+}
+
+// MediaCacheKey 返回文件在缓存中的 key，由素材类型和内容的 sha256 组成
+func MediaCacheKey(mediaType MediaType, data []byte) string {
+	sum := sha256.Sum256(data)
+	return string(mediaType) + ":" + hex.EncodeToString(sum[:])
+}
+
+// Upload 返回 data 对应的 media_id，缓存不存在或已过期时上传
+func (c *MediaCache) Upload(ctx context.Context, mediaType MediaType, filename string, data []byte) (mediaID string, err error) {
+	key := MediaCacheKey(mediaType, data)
+	entry, ok, err := c.store.Get(key)
+	if err != nil {
+		return "", err
+	}
+	if ok && time.Since(entry.CreatedAt) < c.TTL {
+		return entry.MediaID, nil
+	}
+
+	c.mu.Lock()
+	for {
+		call, ok := c.inflight[key]
+		if !ok {
+			break
+		}
+		c.mu.Unlock()
+		select {
+		case <-call.done:
+		case <-ctxDone(ctx):
+			return "", ctx.Err()
+		}
+		// 上传的调用方 ctx 被取消导致上传失败时，由等待的调用方使用自己的 ctx 重新上传
+		if !call.canceled {
+			return call.mediaID, call.err
+		}
+		c.mu.Lock()
+	}
+	call := &mediaUploadCall{done: make(chan struct{})}
+	c.inflight[key] = call
+	c.mu.Unlock()
+
+	call.mediaID, call.err = c.upload(ctx, key, mediaType, filename, data)
+	call.canceled = call.err != nil && ctx != nil && ctx.Err() != nil
+	close(call.done)
+	c.mu.Lock()
+	delete(c.inflight, key)
+	c.mu.Unlock()
+	return call.mediaID, call.err
+}
+
+// UploadReader 读取 r 的全部内容后调用 Upload
+func (c *MediaCache) UploadReader(ctx context.Context, mediaType MediaType, filename string, r io.Reader) (mediaID string, err error) {
+	limit, ok := mediaLimits[mediaType]
+	if !ok {
+		return "", fmt.Errorf("invalid media type: %s", mediaType)
+	}
+	data, err := readLimited(r, limit.maxSize)
+	if err != nil {
+		return "", err
+	}
+	return c.Upload(ctx, mediaType, filename, data)
+}
+
+func (c *MediaCache) upload(ctx context.Context, key string, mediaType MediaType, filename string, data []byte) (string, error) {
+	result, err := c.service.WithContext(ctx).Upload(mediaType, filename, bytes.NewReader(data))
+	if err != nil {
+		return "", err
+	}
+	if err = checkResponse(result); err != nil {
+		return "", err
+	}
+	entry := &MediaCacheEntry{MediaID: result.MediaID, CreatedAt: time.Now()}
+	// created_at 为上传时间戳，以企业微信返回的时间为准
+	if ts, err := strconv.ParseInt(result.CreatedAt, 10, 64); err == nil && ts > 0 {
+		entry.CreatedAt = time.Unix(ts, 0)
+	}
+	if err = c.store.Set(key, entry); err != nil {
+		return "", err
+	}
+	return entry.MediaID, nil
+}
+
+// Invalidate 删除 data 对应的缓存，下次调用 Upload 时重新上传
+func (c *MediaCache) Invalidate(mediaType MediaType, data []byte) error {
+	return c.store.Delete(MediaCacheKey(mediaType, data))
+}
+
+// ctxDone 返回 ctx.Done()，ctx 为 nil 时返回 nil channel，即永不取消
+func ctxDone(ctx context.Context) <-chan struct{} {
+	if ctx == nil {
+		return nil
+	}
+	return ctx.Done()
+}
@@ -0,0 +1,75 @@
+package wecom
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMediaCacheUploadOnce(t *testing.T) {
+	var uploads int32
+	c, _ := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&uploads, 1)
+		fmt.Fprintf(w, `{"errcode":0,"media_id":"m%d","created_at":"%d"}`, n, time.Now().Unix())
+	})
+	cache := c.Media.NewMediaCache(nil)
+	for i := 0; i < 2; i++ {
+		mediaID, err := cache.Upload(context.Background(), MediaTypeFile, "a.txt", []byte("hello"))
+		if err != nil || mediaID != "m1" {
+			t.Fatalf("got %q, %v", mediaID, err)
+		}
+	}
+	if err := cache.Invalidate(MediaTypeFile, []byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+	if mediaID, _ := cache.Upload(context.Background(), MediaTypeFile, "a.txt", []byte("hello")); mediaID != "m2" {
+		t.Fatalf("got %q after invalidate, want m2", mediaID)
+	}
+}
+
+func TestMediaCacheWaiterRetriesCanceledUpload(t *testing.T) {
+	var uploads int32
+	started := make(chan struct{})
+	c, _ := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&uploads, 1) == 1 {
+			// 读完请求体后服务端才能感知到连接断开
+			_, _ = io.Copy(ioutil.Discard, r.Body)
+			close(started)
+			<-r.Context().Done()
+			return
+		}
+		fmt.Fprint(w, `{"errcode":0,"media_id":"m"}`)
+	})
+	cache := c.Media.NewMediaCache(nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	leader := make(chan error, 1)
+	go func() {
+		_, err := cache.Upload(ctx, MediaTypeFile, "a.txt", []byte("hello"))
+		leader <- err
+	}()
+	<-started
+	waiter := make(chan string, 1)
+	go func() {
+		mediaID, err := cache.Upload(context.Background(), MediaTypeFile, "a.txt", []byte("hello"))
+		if err != nil {
+			t.Error(err)
+		}
+		waiter <- mediaID
+	}()
+	// 等待第二个调用方开始等待进行中的上传
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+
+	if err := <-leader; err == nil {
+		t.Fatal("want error for the canceled caller")
+	}
+	if mediaID := <-waiter; mediaID != "m" {
+		t.Fatalf("got %q, want m", mediaID)
+	}
+}
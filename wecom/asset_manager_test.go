@@ -0,0 +1,156 @@
+package wecom
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"testing"
+)
+
+func TestGetRangeHeader(t *testing.T) {
+	tests := []struct {
+		offset, length int64
+		want           string
+	}{
+		{0, -1, ""},
+		{0, 0, ""},
+		{100, 0, "bytes=100-"},
+		{100, -1, "bytes=100-"},
+		{0, 10, "bytes=0-9"},
+		{100, 10, "bytes=100-109"},
+	}
+	for _, tt := range tests {
+		var got string
+		c, _ := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+			got = r.Header.Get("Range")
+			w.WriteHeader(http.StatusPartialContent)
+		})
+		if _, err := c.Media.GetRange("m", ioutil.Discard, tt.offset, tt.length); err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("offset %d, length %d: got Range %q, want %q", tt.offset, tt.length, got, tt.want)
+		}
+	}
+	c, _ := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {})
+	if _, err := c.Media.GetRange("m", ioutil.Discard, -1, 10); err == nil {
+		t.Fatal("want error for negative offset")
+	}
+}
+
+func TestUploadAutoAsyncRequiresScene(t *testing.T) {
+	var scene int
+	c, _ := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case pathMediaUploadByURL:
+			var body UploadByURLRequest
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			scene = body.Scene
+			fmt.Fprint(w, `{"errcode":0,"jobid":"j"}`)
+		case pathMediaUploadByURLResult:
+			fmt.Fprint(w, `{"errcode":0,"status":2,"detail":{"media_id":"m"}}`)
+		default:
+			t.Errorf("unexpected request %s", r.URL.Path)
+		}
+	})
+	src := &MediaSource{Filename: "a.mp4", Size: 50 << 20, URL: "https://example.com/a.mp4", MD5: "x"}
+	if _, err := c.Media.UploadAuto(context.Background(), MediaTypeVideo, src); err == nil {
+		t.Fatal("want error without scene")
+	}
+	src.Scene = UploadSceneWelcome
+	mediaID, err := c.Media.UploadAuto(context.Background(), MediaTypeVideo, src)
+	if err != nil || mediaID != "m" || scene != UploadSceneWelcome {
+		t.Fatalf("got %q, %v, scene %d", mediaID, err, scene)
+	}
+}
+
+func TestUploadAutoMeasuresNonSeekableReader(t *testing.T) {
+	var uploaded int64
+	var sum string
+	c, _ := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case pathMediaUpload:
+			f, _, err := r.FormFile("media")
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			uploaded, _ = io.Copy(ioutil.Discard, f)
+			fmt.Fprint(w, `{"errcode":0,"media_id":"sync"}`)
+		case pathMediaUploadByURL:
+			var body UploadByURLRequest
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			sum = body.MD5
+			fmt.Fprint(w, `{"errcode":0,"jobid":"j"}`)
+		case pathMediaUploadByURLResult:
+			fmt.Fprint(w, `{"errcode":0,"status":2,"detail":{"media_id":"async"}}`)
+		default:
+			t.Errorf("unexpected request %s", r.URL.Path)
+		}
+	})
+	max := mediaLimits[MediaTypeVideo].maxSize
+	for _, n := range []int64{max, max + 1} {
+		data := bytes.Repeat([]byte("x"), int(n))
+		// 隐藏 bytes.Reader 的 Seek 方法
+		src := &MediaSource{
+			Filename: "a.mp4",
+			Reader:   struct{ io.Reader }{bytes.NewReader(data)},
+			URL:      "https://example.com/a.mp4",
+			Scene:    UploadSceneWelcome,
+		}
+		mediaID, err := c.Media.UploadAuto(context.Background(), MediaTypeVideo, src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		digest := md5.Sum(data)
+		switch {
+		case n <= max && (mediaID != "sync" || uploaded != n):
+			t.Fatalf("%d bytes: got %q, uploaded %d bytes", n, mediaID, uploaded)
+		case n > max && (mediaID != "async" || sum != hex.EncodeToString(digest[:])):
+			t.Fatalf("%d bytes: got %q, md5 %s", n, mediaID, sum)
+		}
+	}
+}
+
+func TestGetJSSDK(t *testing.T) {
+	c, _ := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != pathMediaGetJSSDK {
+			t.Errorf("unexpected request %s", r.URL.Path)
+		}
+		switch r.URL.Query().Get("media_id") {
+		case "voice":
+			w.Header().Set("Content-Type", "voice/speex")
+			w.Header().Set("Content-Disposition", `attachment; filename="voice.speex"`)
+			fmt.Fprint(w, "speex-data")
+		default:
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, `{"errcode":40007,"errmsg":"invalid media_id"}`)
+		}
+	})
+
+	var buf bytes.Buffer
+	file, err := c.Media.GetJSSDK("voice", &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if file.Filename != "voice.speex" || file.ContentType != "voice/speex" || file.Written != 10 || buf.String() != "speex-data" {
+		t.Fatalf("unexpected file %+v, content %q", file, buf.String())
+	}
+
+	buf.Reset()
+	_, err = c.Media.GetJSSDK("bad", &buf)
+	var e *Error
+	if !errors.As(err, &e) || e.ErrCode != 40007 {
+		t.Fatalf("got err %v, want errcode 40007", err)
+	}
+	if buf.Len() != 0 {
+		t.Fatalf("error body written to w: %q", buf.String())
+	}
+}
@@ -1,2 +1,328 @@
-// basic.go 对应的是 https://work.weixin.qq.com/api/doc/90000/90135/91020 文档内容
+// authorization.go 对应的是 https://work.weixin.qq.com/api/doc/90000/90135/91020 文档内容
+// 主要实现了网页授权登录（OAuth2）的 API 及 http.Handler 中间件
 package wecom
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+const (
+	pathAuthGetUserInfo   = "/cgi-bin/auth/getuserinfo"
+	pathAuthGetUserDetail = "/cgi-bin/auth/getuserdetail"
+
+	oauth2AuthorizeURL = "https://open.weixin.qq.com/connect/oauth2/authorize"
+
+	// 中间件用于校验 state 的 cookie
+	oauth2StateCookie = "wecom_oauth2_state"
+	// state 的有效期，单位：秒
+	oauth2StateMaxAge = 300
+)
+
+// 网页授权的 scope
+const (
+	// 静默授权，可获取成员的基础信息（userid）
+	ScopeBase = "snsapi_base"
+	// 手动授权，可获取成员的详细信息，包含头像、二维码等敏感信息，需要 agentid
+	ScopePrivateInfo = "snsapi_privateinfo"
+)
+
+type authService service
+
+func (a *authService) WithContext(ctx context.Context) *authService {
+	return &authService{
+		client: a.client,
+		ctx:    ctx,
+	}
+}
+
+// 身份验证：构造网页授权链接，成员授权后会跳转至 redirectURI?code=CODE&state=STATE
+// 参考链接：https://developer.work.weixin.qq.com/document/path/91022
+// scope 为 snsapi_privateinfo 时需通过 NewWithAgentIDOption 设置应用 ID
+// state 最多 128 字节，只能包含 a-zA-Z0-9
+func (a *authService) AuthorizeURL(redirectURI, scope, state string) (string, error) {
+	if scope == "" {
+		scope = ScopeBase
+	}
+	if scope != ScopeBase && scope != ScopePrivateInfo {
+		return "", fmt.Errorf("invalid scope: %s", scope)
+	}
+	if scope == ScopePrivateInfo && a.client.agentID == 0 {
+		return "", errors.New("agent id is required for snsapi_privateinfo")
+	}
+	// 企业微信要求参数按文档顺序排列，因此不使用 url.Values.Encode
+	u := oauth2AuthorizeURL +
+		"?appid=" + url.QueryEscape(a.client.enterpriseID) +
+		"&redirect_uri=" + url.QueryEscape(redirectURI) +
+		"&response_type=code" +
+		"&scope=" + scope +
+		"&state=" + url.QueryEscape(state)
+	if a.client.agentID != 0 {
+		u += fmt.Sprintf("&agentid=%d", a.client.agentID)
+	}
+	return u + "#wechat_redirect", nil
+}
+
+// 访问用户身份
+type AuthUserInfo struct {
+	baseResponse
+	// 企业成员的 userid，非企业成员时为空
+	Userid string `json:"userid"`
+	// 仅 snsapi_privateinfo 时返回，用于获取成员详细信息，有效期为 1800 秒
+	UserTicket string `json:"user_ticket"`
+	// 非企业成员的标识
+	OpenID string `json:"openid"`
+	// 外部联系人 id，仅非企业成员且为企业客户时返回
+	ExternalUserid string `json:"external_userid"`
+}
+
+// 身份验证：获取访问用户身份，code 只能使用一次，5 分钟未被使用自动过期
+// 参考链接：https://developer.work.weixin.qq.com/document/path/91023
+func (a *authService) GetUserInfo(code string) (result *AuthUserInfo, err error) {
+	failCount := -1
+	// 默认尝试一次，即不进行失败重试
+	for failCount < a.client.maxRetryTimes {
+		failCount++
+		var req *http.Request
+		req, err = a.client.newRequest(http.MethodGet, pathAuthGetUserInfo, nil, "code="+url.QueryEscape(code))
+		if err != nil {
+			continue // 如果循环结束，则会返回该 err
+		}
+		result = new(AuthUserInfo)
+		err = (*service)(a).doRequest(req, result)
+		if err != nil {
+			continue // 如果循环结束，则会返回该 err
+		}
+		// 成功，return
+		return result, nil
+	}
+	// 失败，返回最后一次请求的 err
+	return nil, err
+}
+
+// 访问用户敏感信息
+type AuthUserDetail struct {
+	baseResponse
+	Userid string `json:"userid"`
+	// 性别，0 表示未定义，1 表示男性，2 表示女性
+	Gender  string `json:"gender"`
+	Avatar  string `json:"avatar"`
+	QrCode  string `json:"qr_code"`
+	Mobile  string `json:"mobile"`
+	Email   string `json:"email"`
+	BizMail string `json:"biz_mail"`
+	Address string `json:"address"`
+}
+
+// 身份验证：获取访问用户敏感信息，需成员在 snsapi_privateinfo 授权页确认授权
+// 参考链接：https://developer.work.weixin.qq.com/document/path/95833
+func (a *authService) GetUserDetail(userTicket string) (result *AuthUserDetail, err error) {
+	failCount := -1
+	// 默认尝试一次，即不进行失败重试
+	for failCount < a.client.maxRetryTimes {
+		failCount++
+		body := struct {
+			UserTicket string `json:"user_ticket"`
+		}{UserTicket: userTicket}
+		var req *http.Request
+		req, err = a.client.newRequest(http.MethodPost, pathAuthGetUserDetail, body)
+		if err != nil {
+			continue // 如果循环结束，则会返回该 err
+		}
+		result = new(AuthUserDetail)
+		err = (*service)(a).doRequest(req, result)
+		if err != nil {
+			continue // 如果循环结束，则会返回该 err
+		}
+		// 成功，return
+		return result, nil
+	}
+	// 失败，返回最后一次请求的 err
+	return nil, err
+}
+
+// AuthIdentity 网页授权得到的访问用户身份
+type AuthIdentity struct {
+	Userid         string
+	OpenID         string
+	ExternalUserid string
+	// 仅 snsapi_privateinfo 且成员确认授权时有值
+	Detail *AuthUserDetail
+}
+
+type identityContextKey struct{}
+
+// IdentityFromContext 获取 AuthMiddleware 存入 request context 的访问用户身份
+func IdentityFromContext(ctx context.Context) (*AuthIdentity, bool) {
+	id, ok := ctx.Value(identityContextKey{}).(*AuthIdentity)
+	return id, ok && id != nil
+}
+
+// AuthMiddlewareOptions 网页授权中间件的选项，零值均使用默认值
+type AuthMiddlewareOptions struct {
+	// 授权的 scope，默认为 snsapi_base
+	Scope string
+	// 应用对外访问的地址，如 https://app.example.com，用于拼接 redirect_uri
+	// 默认根据请求的 Host 及 X-Forwarded-Proto 推断，域名须为应用的可信域名
+	BaseURL string
+	// 从会话中加载已登录的身份，返回 nil 时发起授权，默认每次请求都发起授权
+	Load func(r *http.Request) *AuthIdentity
+	// 授权成功后保存身份，可用于写入会话
+	// 设置后会在保存身份后重定向至去掉 code 及 state 参数的地址，避免刷新页面时重复使用 code，
+	// 此时必须同时设置 Load，否则重定向后会再次发起授权；未设置时直接由 next 处理回调请求
+	Save func(w http.ResponseWriter, r *http.Request, identity *AuthIdentity)
+	// 授权失败时的处理，默认返回 401 或 403，响应内容不包含具体的错误信息
+	OnError func(w http.ResponseWriter, r *http.Request, status int, err error)
+}
+
+// AuthMiddleware 身份验证：网页授权中间件
+// 未登录的 GET、HEAD 请求会被重定向至授权页，授权回调时以 code 换取身份，
+// 设置了 Save 时保存身份后重定向至原地址，否则将身份存入 request context 后由 next 处理，
+// 可通过 IdentityFromContext 获取身份；其他方法的未登录请求返回 401
+// 设置了 Save 而未设置 Load 时 panic
+func (a *authService) AuthMiddleware(opts AuthMiddlewareOptions, next http.Handler) http.Handler {
+	if opts.Save != nil && opts.Load == nil {
+		panic("wecom: AuthMiddlewareOptions.Save requires Load")
+	}
+	if opts.Scope == "" {
+		opts.Scope = ScopeBase
+	}
+	if opts.OnError == nil {
+		opts.OnError = func(w http.ResponseWriter, r *http.Request, status int, err error) {
+			// 错误信息可能包含企业微信返回的 errmsg，不返回给访问用户
+			http.Error(w, http.StatusText(status), status)
+		}
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if opts.Load != nil {
+			if identity := opts.Load(r); identity != nil {
+				next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), identityContextKey{}, identity)))
+				return
+			}
+		}
+
+		query := r.URL.Query()
+		if code := query.Get("code"); code != "" {
+			if err := checkOAuth2State(w, r, query.Get("state")); err != nil {
+				opts.OnError(w, r, http.StatusForbidden, err)
+				return
+			}
+			identity, err := a.WithContext(r.Context()).exchangeIdentity(code, opts.Scope)
+			if err != nil {
+				opts.OnError(w, r, http.StatusUnauthorized, err)
+				return
+			}
+			if opts.Save != nil {
+				opts.Save(w, r, identity)
+				http.Redirect(w, r, oauth2RedirectURI(r, opts.BaseURL), http.StatusFound)
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), identityContextKey{}, identity)))
+			return
+		}
+
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			opts.OnError(w, r, http.StatusUnauthorized, errors.New("wecom: unauthorized"))
+			return
+		}
+		state, err := newOAuth2State()
+		if err != nil {
+			opts.OnError(w, r, http.StatusInternalServerError, err)
+			return
+		}
+		redirectURI := oauth2RedirectURI(r, opts.BaseURL)
+		authURL, err := a.AuthorizeURL(redirectURI, opts.Scope, state)
+		if err != nil {
+			opts.OnError(w, r, http.StatusInternalServerError, err)
+			return
+		}
+		http.SetCookie(w, &http.Cookie{
+			Name:     oauth2StateCookie,
+			Value:    state,
+			Path:     "/",
+			MaxAge:   oauth2StateMaxAge,
+			Secure:   isHTTPS(r),
+			HttpOnly: true,
+			SameSite: http.SameSiteLaxMode,
+		})
+		http.Redirect(w, r, authURL, http.StatusFound)
+	})
+}
+
+// exchangeIdentity 以 code 换取身份，snsapi_privateinfo 时同时获取敏感信息
+func (a *authService) exchangeIdentity(code, scope string) (*AuthIdentity, error) {
+	info, err := a.GetUserInfo(code)
+	if err == nil {
+		err = checkResponse(info)
+	}
+	if err != nil {
+		return nil, err
+	}
+	identity := &AuthIdentity{
+		Userid:         info.Userid,
+		OpenID:         info.OpenID,
+		ExternalUserid: info.ExternalUserid,
+	}
+	// 成员未确认授权时不返回 user_ticket，此时仅有基础信息
+	if scope == ScopePrivateInfo && info.UserTicket != "" {
+		detail, err := a.GetUserDetail(info.UserTicket)
+		if err == nil {
+			err = checkResponse(detail)
+		}
+		if err != nil {
+			return nil, err
+		}
+		identity.Detail = detail
+	}
+	return identity, nil
+}
+
+// checkOAuth2State 校验回调的 state 与发起授权时写入 cookie 的一致，并清除该 cookie
+func checkOAuth2State(w http.ResponseWriter, r *http.Request, state string) error {
+	cookie, err := r.Cookie(oauth2StateCookie)
+	if err != nil || cookie.Value == "" || cookie.Value != state {
+		return errors.New("wecom: invalid oauth2 state")
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     oauth2StateCookie,
+		Path:     "/",
+		MaxAge:   -1,
+		Secure:   isHTTPS(r),
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return nil
+}
+
+func newOAuth2State() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// oauth2RedirectURI 当前请求的完整地址，去掉 code 及 state 参数
+func oauth2RedirectURI(r *http.Request, baseURL string) string {
+	query := r.URL.Query()
+	query.Del("code")
+	query.Del("state")
+	u := url.URL{Path: r.URL.Path, RawQuery: query.Encode()}
+	if baseURL == "" {
+		scheme := "http"
+		if isHTTPS(r) {
+			scheme = "https"
+		}
+		baseURL = scheme + "://" + r.Host
+	}
+	return baseURL + u.String()
+}
+
+func isHTTPS(r *http.Request) bool {
+	return r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https"
+}
@@ -0,0 +1,116 @@
+package wecom
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func newAuthTestMiddleware(t *testing.T, opts AuthMiddlewareOptions) http.Handler {
+	c, _ := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("code") != "good" {
+			fmt.Fprint(w, `{"errcode":40029,"errmsg":"invalid code, hint: [secret]"}`)
+			return
+		}
+		fmt.Fprint(w, `{"errcode":0,"userid":"u"}`)
+	})
+	return c.Auth.AuthMiddleware(opts, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		identity, _ := IdentityFromContext(r.Context())
+		fmt.Fprint(w, "hello "+identity.Userid)
+	}))
+}
+
+func TestAuthMiddlewareRedirect(t *testing.T) {
+	h := newAuthTestMiddleware(t, AuthMiddlewareOptions{})
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "http://app.example.com/page?x=1", nil))
+	if w.Code != http.StatusFound {
+		t.Fatalf("got status %d", w.Code)
+	}
+	u, _ := url.Parse(w.Header().Get("Location"))
+	if got := u.Query().Get("redirect_uri"); got != "http://app.example.com/page?x=1" {
+		t.Fatalf("got redirect_uri %q", got)
+	}
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Value != u.Query().Get("state") {
+		t.Fatalf("state cookie not set: %v", cookies)
+	}
+}
+
+func TestAuthMiddlewareCallback(t *testing.T) {
+	var saved *AuthIdentity
+	h := newAuthTestMiddleware(t, AuthMiddlewareOptions{
+		Load: func(r *http.Request) *AuthIdentity { return nil },
+		Save: func(w http.ResponseWriter, r *http.Request, identity *AuthIdentity) { saved = identity },
+	})
+	r := httptest.NewRequest(http.MethodGet, "http://app.example.com/page?x=1&code=good&state=s", nil)
+	r.AddCookie(&http.Cookie{Name: oauth2StateCookie, Value: "s"})
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusFound || w.Header().Get("Location") != "http://app.example.com/page?x=1" {
+		t.Fatalf("got status %d, location %q", w.Code, w.Header().Get("Location"))
+	}
+	if saved == nil || saved.Userid != "u" {
+		t.Fatalf("got saved identity %+v", saved)
+	}
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].MaxAge >= 0 || cookies[0].SameSite != http.SameSiteLaxMode {
+		t.Fatalf("state cookie not cleared: %+v", cookies)
+	}
+}
+
+func TestAuthMiddlewareCallbackWithoutSave(t *testing.T) {
+	h := newAuthTestMiddleware(t, AuthMiddlewareOptions{})
+	r := httptest.NewRequest(http.MethodGet, "http://app.example.com/page?code=good&state=s", nil)
+	r.AddCookie(&http.Cookie{Name: oauth2StateCookie, Value: "s"})
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusOK || w.Body.String() != "hello u" {
+		t.Fatalf("got status %d, body %q", w.Code, w.Body.String())
+	}
+}
+
+func TestAuthMiddlewareErrors(t *testing.T) {
+	h := newAuthTestMiddleware(t, AuthMiddlewareOptions{})
+	tests := []struct {
+		name   string
+		url    string
+		cookie string
+		status int
+	}{
+		{"state mismatch", "http://app.example.com/?code=good&state=s", "other", http.StatusForbidden},
+		{"invalid code", "http://app.example.com/?code=bad&state=s", "s", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, tt.url, nil)
+		r.AddCookie(&http.Cookie{Name: oauth2StateCookie, Value: tt.cookie})
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if w.Code != tt.status {
+			t.Errorf("%s: got status %d, want %d", tt.name, w.Code, tt.status)
+		}
+		if strings.Contains(w.Body.String(), "secret") || strings.Contains(w.Body.String(), "errcode") {
+			t.Errorf("%s: response leaks error detail: %q", tt.name, w.Body.String())
+		}
+	}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "http://app.example.com/", nil))
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("got status %d for POST, want 401", w.Code)
+	}
+}
+
+func TestAuthMiddlewareSaveRequiresLoad(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("want panic when Save is set without Load")
+		}
+	}()
+	newAuthTestMiddleware(t, AuthMiddlewareOptions{
+		Save: func(w http.ResponseWriter, r *http.Request, identity *AuthIdentity) {},
+	})
+}
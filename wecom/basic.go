@@ -24,21 +24,36 @@ type Basic struct {
 
 // 一般仅在 token 过期的情况下刷新 token
 // 参数要求及含义参考：https://work.weixin.qq.com/api/doc/90000/90135/91039
+// stale 为调用方已知失效的 token，若此时 token 已被其他 goroutine 刷新，则直接返回新的 token，
+// 从而保证并发调用时只会请求一次 gettoken
+// 获取失败（如 secret 错误）时返回 err，不会更新已有的 token
 // TODO 优化更新时机
-// TODO 处理失败
-func (b *basicService) refreshAccessToken() {
+func (b *basicService) refreshAccessToken(stale string) (string, error) {
+	b.client.refreshMu.Lock()
+	defer b.client.refreshMu.Unlock()
+
+	b.client.mu.RLock()
+	token := b.client.token
+	b.client.mu.RUnlock()
+	if token != stale {
+		return token, nil
+	}
+
 	// 调用 API 获取 token
 	req, err := b.client.newRequest(http.MethodGet, pathGetToken, nil, "corpid="+b.client.enterpriseID, "corpsecret="+b.client.agentSecret)
 	if err != nil {
-		panic(err)
+		return "", err
 	}
 	result := new(Basic)
-	err = b.client.do(req, result)
-	if err != nil {
-		panic(err)
+	if err = b.client.do(req, result); err != nil {
+		return "", err
+	}
+	if err = checkResponse(result); err != nil {
+		return "", err
 	}
 	b.client.mu.Lock()
 	defer b.client.mu.Unlock()
 	b.client.token = result.AccessToken
 	b.client.expireAt = time.Now().Unix() + result.ExpiresIn
+	return b.client.token, nil
 }
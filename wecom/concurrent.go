@@ -0,0 +1,37 @@
+package wecom
+
+import (
+	"context"
+	"sync"
+)
+
+// parallel 以不超过 workers 的并发度对下标 [0, n) 调用 fn，返回的 errs 与下标一一对应
+// ctx 被取消后，尚未开始的任务不再执行，对应的 err 为 ctx.Err()，ctx 可以为 nil
+func parallel(ctx context.Context, n, workers int, fn func(k int) error) []error {
+	errs := make([]error, n)
+	if workers > n {
+		workers = n
+	}
+
+	jobs := make(chan int)
+	wg := &sync.WaitGroup{}
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for k := range jobs {
+				if ctx != nil && ctx.Err() != nil {
+					errs[k] = ctx.Err()
+					continue
+				}
+				errs[k] = fn(k)
+			}
+		}()
+	}
+	for k := 0; k < n; k++ {
+		jobs <- k
+	}
+	close(jobs)
+	wg.Wait()
+	return errs
+}
@@ -0,0 +1,44 @@
+package wecom
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestParallel(t *testing.T) {
+	var running, peak int32
+	errs := parallel(context.Background(), 20, 3, func(k int) error {
+		n := atomic.AddInt32(&running, 1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		time.Sleep(time.Millisecond)
+		atomic.AddInt32(&running, -1)
+		if k%2 == 1 {
+			return errors.New("odd")
+		}
+		return nil
+	})
+	if peak > 3 {
+		t.Fatalf("peak concurrency %d, want <= 3", peak)
+	}
+	for k, err := range errs {
+		if (err != nil) != (k%2 == 1) {
+			t.Fatalf("errs[%d] = %v", k, err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for _, err := range parallel(ctx, 5, 2, func(int) error { return nil }) {
+		if err != context.Canceled {
+			t.Fatalf("got %v, want context.Canceled", err)
+		}
+	}
+}
@@ -1,2 +1,257 @@
-// basic.go 对应的是 https://work.weixin.qq.com/api/doc/90000/90135/93360 文档内容
+// enterprise_enterconnect.go 对应的是 https://work.weixin.qq.com/api/doc/90000/90135/93360 文档内容
+// 主要实现了互联企业的通讯录查询及发送应用消息的 API
+// 互联企业的成员 ID 格式为 "corpid/userid"，部门 ID 格式为 "linked_id/department_id"
 package wecom
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+const (
+	pathLinkedPermList       = "/cgi-bin/linkedcorp/agent/get_perm_list"
+	pathLinkedUserGet        = "/cgi-bin/linkedcorp/user/get"
+	pathLinkedUserSimpleList = "/cgi-bin/linkedcorp/user/simplelist"
+	pathLinkedUserList       = "/cgi-bin/linkedcorp/user/list"
+	pathLinkedDeptList       = "/cgi-bin/linkedcorp/department/list"
+	pathLinkedMessageSend    = "/cgi-bin/linkedcorp/message/send"
+)
+
+type linkedCorpService service
+
+func (l *linkedCorpService) WithContext(ctx context.Context) *linkedCorpService {
+	return &linkedCorpService{
+		client: l.client,
+		ctx:    ctx,
+	}
+}
+
+// 应用的可见范围
+type LinkedCorpPermList struct {
+	baseResponse
+	Userids       []string `json:"userids"`
+	DepartmentIDs []string `json:"department_ids"`
+}
+
+// 互联企业：获取应用的可见范围
+// 参考链接：https://developer.work.weixin.qq.com/document/path/93172
+func (l *linkedCorpService) GetPermList() (result *LinkedCorpPermList, err error) {
+	failCount := -1
+	// 默认尝试一次，即不进行失败重试
+	for failCount < l.client.maxRetryTimes {
+		failCount++
+		var req *http.Request
+		req, err = l.client.newRequest(http.MethodPost, pathLinkedPermList, struct{}{})
+		if err != nil {
+			continue // 如果循环结束，则会返回该 err
+		}
+		result = new(LinkedCorpPermList)
+		err = (*service)(l).doRequest(req, result)
+		if err != nil {
+			continue // 如果循环结束，则会返回该 err
+		}
+		// 成功，return
+		return result, nil
+	}
+	// 失败，返回最后一次请求的 err
+	return nil, err
+}
+
+// 互联企业成员，字段含义与 User 相同
+type LinkedCorpUser struct {
+	Userid     string   `json:"userid"`
+	Name       string   `json:"name"`
+	Department []string `json:"department"`
+	Mobile     string   `json:"mobile,omitempty"`
+	Telephone  string   `json:"telephone,omitempty"`
+	Email      string   `json:"email,omitempty"`
+	Position   string   `json:"position,omitempty"`
+	CorpID     string   `json:"corpid"`
+	Extattr    *Extattr `json:"extattr,omitempty"`
+}
+
+type LinkedCorpUserInfo struct {
+	baseResponse
+	UserInfo LinkedCorpUser `json:"user_info"`
+}
+
+type linkedCorpQuery struct {
+	Userid       string `json:"userid,omitempty"`
+	DepartmentID string `json:"department_id,omitempty"`
+}
+
+// 互联企业：获取互联企业成员详细信息
+// 参考链接：https://developer.work.weixin.qq.com/document/path/93171
+// userID 格式为 "corpid/userid"
+func (l *linkedCorpService) GetUser(userID string) (result *LinkedCorpUserInfo, err error) {
+	failCount := -1
+	// 默认尝试一次，即不进行失败重试
+	for failCount < l.client.maxRetryTimes {
+		failCount++
+		body := linkedCorpQuery{Userid: userID}
+		var req *http.Request
+		req, err = l.client.newRequest(http.MethodPost, pathLinkedUserGet, body)
+		if err != nil {
+			continue // 如果循环结束，则会返回该 err
+		}
+		result = new(LinkedCorpUserInfo)
+		err = (*service)(l).doRequest(req, result)
+		if err != nil {
+			continue // 如果循环结束，则会返回该 err
+		}
+		// 成功，return
+		return result, nil
+	}
+	// 失败，返回最后一次请求的 err
+	return nil, err
+}
+
+type LinkedCorpUserList struct {
+	baseResponse
+	Userlist []LinkedCorpUser `json:"userlist"`
+}
+
+// 互联企业：获取互联企业部门成员，仅返回 userid、name、department、corpid
+// 参考链接：https://developer.work.weixin.qq.com/document/path/93168
+// departmentID 格式为 "linked_id/department_id"
+func (l *linkedCorpService) ListUser(departmentID string) (result *LinkedCorpUserList, err error) {
+	return l.listUser(pathLinkedUserSimpleList, departmentID)
+}
+
+// 互联企业：获取互联企业部门成员详情
+// 参考链接：https://developer.work.weixin.qq.com/document/path/93169
+// departmentID 格式为 "linked_id/department_id"
+func (l *linkedCorpService) ListUserDetail(departmentID string) (result *LinkedCorpUserList, err error) {
+	return l.listUser(pathLinkedUserList, departmentID)
+}
+
+func (l *linkedCorpService) listUser(path, departmentID string) (result *LinkedCorpUserList, err error) {
+	failCount := -1
+	// 默认尝试一次，即不进行失败重试
+	for failCount < l.client.maxRetryTimes {
+		failCount++
+		body := linkedCorpQuery{DepartmentID: departmentID}
+		var req *http.Request
+		req, err = l.client.newRequest(http.MethodPost, path, body)
+		if err != nil {
+			continue // 如果循环结束，则会返回该 err
+		}
+		result = new(LinkedCorpUserList)
+		err = (*service)(l).doRequest(req, result)
+		if err != nil {
+			continue // 如果循环结束，则会返回该 err
+		}
+		// 成功，return
+		return result, nil
+	}
+	// 失败，返回最后一次请求的 err
+	return nil, err
+}
+
+// 互联企业部门，字段含义与 Department 相同
+type LinkedCorpDepartment struct {
+	DepartmentID   string `json:"department_id"`
+	DepartmentName string `json:"department_name"`
+	Parentid       string `json:"parentid"`
+	Order          int    `json:"order"`
+}
+
+type LinkedCorpDepartmentList struct {
+	baseResponse
+	DepartmentList []LinkedCorpDepartment `json:"department_list"`
+}
+
+// 互联企业：获取互联企业部门列表
+// 参考链接：https://developer.work.weixin.qq.com/document/path/93170
+// departmentID 格式为 "linked_id/department_id"
+func (l *linkedCorpService) DepartmentList(departmentID string) (result *LinkedCorpDepartmentList, err error) {
+	failCount := -1
+	// 默认尝试一次，即不进行失败重试
+	for failCount < l.client.maxRetryTimes {
+		failCount++
+		body := linkedCorpQuery{DepartmentID: departmentID}
+		var req *http.Request
+		req, err = l.client.newRequest(http.MethodPost, pathLinkedDeptList, body)
+		if err != nil {
+			continue // 如果循环结束，则会返回该 err
+		}
+		result = new(LinkedCorpDepartmentList)
+		err = (*service)(l).doRequest(req, result)
+		if err != nil {
+			continue // 如果循环结束，则会返回该 err
+		}
+		// 成功，return
+		return result, nil
+	}
+	// 失败，返回最后一次请求的 err
+	return nil, err
+}
+
+// LinkedCorpMessage 互联企业消息，与应用消息不同，接收人为数组
+// touser、toparty、totag 不能同时为空，除非 ToAll 为 1
+type LinkedCorpMessage struct {
+	// 成员 ID 列表，格式为 "corpid/userid"，本企业成员可直接填 userid
+	ToUser []string `json:"touser,omitempty"`
+	// 部门 ID 列表，格式为 "linked_id/department_id"
+	ToParty []string `json:"toparty,omitempty"`
+	// 本企业的标签 ID 列表
+	ToTag []string `json:"totag,omitempty"`
+	// 1 表示发送给应用可见范围内的所有人
+	ToAll int `json:"toall,omitempty"`
+	// 为 0 时使用 NewWithAgentIDOption 设置的应用 ID
+	AgentID int `json:"agentid"`
+	MessageContent
+	Safe int `json:"safe,omitempty"`
+}
+
+// NewLinkedCorpMessage 使用 content 创建互联企业消息，content 与应用消息共用
+func NewLinkedCorpMessage(content *MessageContent) *LinkedCorpMessage {
+	return &LinkedCorpMessage{MessageContent: *content}
+}
+
+type LinkedCorpMessageResp struct {
+	baseResponse
+	InvalidUser  []string `json:"invaliduser,omitempty"`
+	InvalidParty []string `json:"invalidparty,omitempty"`
+	InvalidTag   []string `json:"invalidtag,omitempty"`
+}
+
+// 互联企业：发送应用消息，不支持模板卡片消息
+// 参考链接：https://developer.work.weixin.qq.com/document/path/90250
+func (l *linkedCorpService) SendMessage(msg *LinkedCorpMessage) (result *LinkedCorpMessageResp, err error) {
+	if msg.ToAll == 0 && len(msg.ToUser) == 0 && len(msg.ToParty) == 0 && len(msg.ToTag) == 0 {
+		return nil, errors.New("touser, toparty and totag cannot be empty at the same time")
+	}
+	if msg.MsgType == MsgTypeTemplateCard {
+		return nil, errors.New("msgtype template_card is not supported by linkedcorp")
+	}
+	if msg.AgentID == 0 {
+		if l.client.agentID == 0 {
+			return nil, errors.New("agent id is required")
+		}
+		c := *msg
+		c.AgentID = l.client.agentID
+		msg = &c
+	}
+
+	failCount := -1
+	// 默认尝试一次，即不进行失败重试
+	for failCount < l.client.maxRetryTimes {
+		failCount++
+		var req *http.Request
+		req, err = l.client.newRequest(http.MethodPost, pathLinkedMessageSend, msg)
+		if err != nil {
+			continue // 如果循环结束，则会返回该 err
+		}
+		result = new(LinkedCorpMessageResp)
+		err = (*service)(l).doRequest(req, result)
+		if err != nil {
+			continue // 如果循环结束，则会返回该 err
+		}
+		// 成功，return
+		return result, nil
+	}
+	// 失败，返回最后一次请求的 err
+	return nil, err
+}
@@ -0,0 +1,81 @@
+package wecom
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestLinkedCorpSendMessage(t *testing.T) {
+	var body map[string]interface{}
+	c, _ := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != pathLinkedMessageSend {
+			t.Errorf("unexpected request: %s", r.URL.Path)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Error(err)
+		}
+		fmt.Fprint(w, `{"errcode":0,"invaliduser":["corp/x"]}`)
+	}, NewWithAgentIDOption(7))
+
+	msg := NewLinkedCorpMessage(NewTextContent("hi"))
+	msg.ToUser = []string{"corp/u", "u2"}
+	result, err := c.LinkedCorp.SendMessage(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(result.InvalidUser, []string{"corp/x"}) {
+		t.Fatalf("got invaliduser %v", result.InvalidUser)
+	}
+	if !reflect.DeepEqual(body["touser"], []interface{}{"corp/u", "u2"}) {
+		t.Fatalf("got touser %#v, want an array", body["touser"])
+	}
+	if body["agentid"] != float64(7) || body["msgtype"] != "text" {
+		t.Fatalf("got agentid %v, msgtype %v", body["agentid"], body["msgtype"])
+	}
+	if text, _ := body["text"].(map[string]interface{}); text["content"] != "hi" {
+		t.Fatalf("got text %v", body["text"])
+	}
+	if _, ok := body["toparty"]; ok {
+		t.Fatal("empty toparty should be omitted")
+	}
+	if msg.AgentID != 0 {
+		t.Fatal("caller's message modified")
+	}
+}
+
+func TestLinkedCorpSendMessageValidate(t *testing.T) {
+	c, _ := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request: %s", r.URL.Path)
+	})
+	cases := map[string]*LinkedCorpMessage{
+		"no recipients": NewLinkedCorpMessage(NewTextContent("hi")),
+		"template card": {ToAll: 1, MessageContent: MessageContent{MsgType: MsgTypeTemplateCard}},
+		"no agent id":   {ToAll: 1, MessageContent: *NewTextContent("hi")},
+	}
+	for name, msg := range cases {
+		if _, err := c.LinkedCorp.SendMessage(msg); err == nil {
+			t.Errorf("%s: want error", name)
+		}
+	}
+}
+
+func TestLinkedCorpGetUser(t *testing.T) {
+	c, _ := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		var body linkedCorpQuery
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		if r.URL.Path != pathLinkedUserGet || body.Userid != "corp/u" {
+			t.Errorf("unexpected request: %s %+v", r.URL.Path, body)
+		}
+		fmt.Fprint(w, `{"errcode":0,"user_info":{"userid":"corp/u","department":["1/2"],"corpid":"corp"}}`)
+	})
+	result, err := c.LinkedCorp.GetUser("corp/u")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.UserInfo.CorpID != "corp" || result.UserInfo.Department[0] != "1/2" {
+		t.Fatalf("unexpected user: %+v", result.UserInfo)
+	}
+}
@@ -0,0 +1,203 @@
+// message_appchat.go 对应的是 https://developer.work.weixin.qq.com/document/path/90244 文档内容
+// 主要实现了群聊会话的创建、修改、获取及发送消息的 API
+package wecom
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+const (
+	pathAppChatCreate = "/cgi-bin/appchat/create"
+	pathAppChatUpdate = "/cgi-bin/appchat/update"
+	pathAppChatGet    = "/cgi-bin/appchat/get"
+	pathAppChatSend   = "/cgi-bin/appchat/send"
+)
+
+type appChatService service
+
+func (a *appChatService) WithContext(ctx context.Context) *appChatService {
+	return &appChatService{
+		client: a.client,
+		ctx:    ctx,
+	}
+}
+
+// AppChat 群聊会话
+type AppChat struct {
+	// 群聊 ID，创建时不填则由企业微信生成
+	ChatID string `json:"chatid,omitempty"`
+	Name   string `json:"name,omitempty"`
+	// 群主，创建时不填则从 Userlist 中随机选取
+	Owner string `json:"owner,omitempty"`
+	// 群成员，至少 2 人，至多 2000 人
+	Userlist []string `json:"userlist"`
+	// 群聊类型，0 表示通过接口创建的群聊
+	ChatType int `json:"chat_type,omitempty"`
+}
+
+type AppChatResp struct {
+	baseResponse
+	ChatID string `json:"chatid,omitempty"`
+}
+
+// Create 群聊会话：创建群聊会话
+// 参考链接：https://developer.work.weixin.qq.com/document/path/90245
+func (a *appChatService) Create(chat *AppChat) (result *AppChatResp, err error) {
+	if len(chat.Userlist) < 2 || len(chat.Userlist) > 2000 {
+		return nil, fmt.Errorf("userlist must have 2 ~ 2000 members, got %d", len(chat.Userlist))
+	}
+	if chat.Owner != "" && !containsString(chat.Userlist, chat.Owner) {
+		return nil, fmt.Errorf("owner %s must be in userlist", chat.Owner)
+	}
+
+	failCount := -1
+	// 默认尝试一次，即不进行失败重试
+	for failCount < a.client.maxRetryTimes {
+		failCount++
+		var req *http.Request
+		req, err = a.client.newRequest(http.MethodPost, pathAppChatCreate, chat)
+		if err != nil {
+			continue // 如果循环结束，则会返回该 err
+		}
+		result = new(AppChatResp)
+		err = (*service)(a).doRequest(req, result)
+		if err != nil {
+			continue // 如果循环结束，则会返回该 err
+		}
+		// 成功，return
+		return result, nil
+	}
+	// 失败，返回最后一次请求的 err
+	return nil, err
+}
+
+// AppChatUpdate 修改群聊会话，未填写的字段不做修改
+type AppChatUpdate struct {
+	ChatID      string   `json:"chatid"`
+	Name        string   `json:"name,omitempty"`
+	Owner       string   `json:"owner,omitempty"`
+	AddUserList []string `json:"add_user_list,omitempty"`
+	DelUserList []string `json:"del_user_list,omitempty"`
+}
+
+// Update 群聊会话：修改群聊会话
+// 参考链接：https://developer.work.weixin.qq.com/document/path/90246
+func (a *appChatService) Update(update *AppChatUpdate) (result *AppChatResp, err error) {
+	if update.ChatID == "" {
+		return nil, errors.New("chat id is required")
+	}
+	if update.Owner != "" && containsString(update.DelUserList, update.Owner) {
+		return nil, fmt.Errorf("owner %s cannot be removed", update.Owner)
+	}
+
+	failCount := -1
+	// 默认尝试一次，即不进行失败重试
+	for failCount < a.client.maxRetryTimes {
+		failCount++
+		var req *http.Request
+		req, err = a.client.newRequest(http.MethodPost, pathAppChatUpdate, update)
+		if err != nil {
+			continue // 如果循环结束，则会返回该 err
+		}
+		result = new(AppChatResp)
+		err = (*service)(a).doRequest(req, result)
+		if err != nil {
+			continue // 如果循环结束，则会返回该 err
+		}
+		// 成功，return
+		return result, nil
+	}
+	// 失败，返回最后一次请求的 err
+	return nil, err
+}
+
+type AppChatInfo struct {
+	baseResponse
+	ChatInfo AppChat `json:"chat_info"`
+}
+
+// Get 群聊会话：获取群聊会话
+// 参考链接：https://developer.work.weixin.qq.com/document/path/90247
+func (a *appChatService) Get(chatID string) (result *AppChatInfo, err error) {
+	failCount := -1
+	// 默认尝试一次，即不进行失败重试
+	for failCount < a.client.maxRetryTimes {
+		failCount++
+		var req *http.Request
+		req, err = a.client.newRequest(http.MethodGet, pathAppChatGet, nil, "chatid="+chatID)
+		if err != nil {
+			continue // 如果循环结束，则会返回该 err
+		}
+		result = new(AppChatInfo)
+		err = (*service)(a).doRequest(req, result)
+		if err != nil {
+			continue // 如果循环结束，则会返回该 err
+		}
+		// 成功，return
+		return result, nil
+	}
+	// 失败，返回最后一次请求的 err
+	return nil, err
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// 群聊会话不支持的消息类型
+var appChatUnsupported = map[MsgType]struct{}{
+	MsgTypeMiniprogramNotice: {},
+	MsgTypeTemplateCard:      {},
+}
+
+type appChatMessage struct {
+	ChatID string `json:"chatid"`
+	MessageContent
+	Safe int `json:"safe,omitempty"`
+}
+
+// Send 群聊会话：推送消息到群聊会话
+// 参考链接：https://developer.work.weixin.qq.com/document/path/90248
+// content 与应用消息共用，可通过 NewTextContent、NewMarkdownContent 等创建；safe 含义同 Message.Safe
+func (a *appChatService) Send(chatID string, content *MessageContent, safe int) (result *AppChatResp, err error) {
+	if chatID == "" {
+		return nil, errors.New("chat id is required")
+	}
+	if _, ok := appChatUnsupported[content.MsgType]; ok {
+		return nil, fmt.Errorf("msgtype %s is not supported by appchat", content.MsgType)
+	}
+
+	failCount := -1
+	// 默认尝试一次，即不进行失败重试
+	for failCount < a.client.maxRetryTimes {
+		failCount++
+		body := appChatMessage{ChatID: chatID, MessageContent: *content, Safe: safe}
+		var req *http.Request
+		req, err = a.client.newRequest(http.MethodPost, pathAppChatSend, body)
+		if err != nil {
+			continue // 如果循环结束，则会返回该 err
+		}
+		result = new(AppChatResp)
+		err = (*service)(a).doRequest(req, result)
+		if err != nil {
+			continue // 如果循环结束，则会返回该 err
+		}
+		// 成功，return
+		return result, nil
+	}
+	// 失败，返回最后一次请求的 err
+	return nil, err
+}
+
+// SendMessage 将应用消息的内容推送到群聊会话，忽略 msg 中的接收人
+func (a *appChatService) SendMessage(chatID string, msg *Message) (result *AppChatResp, err error) {
+	return a.Send(chatID, &msg.MessageContent, msg.Safe)
+}
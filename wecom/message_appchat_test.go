@@ -0,0 +1,87 @@
+package wecom
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestAppChat(t *testing.T) {
+	var path, query string
+	var body map[string]interface{}
+	c, _ := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		path, query = r.URL.Path, r.URL.Query().Get("chatid")
+		body = nil
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		switch path {
+		case pathAppChatCreate:
+			fmt.Fprint(w, `{"errcode":0,"chatid":"c1"}`)
+		case pathAppChatGet:
+			fmt.Fprint(w, `{"errcode":0,"chat_info":{"chatid":"c1","name":"n","owner":"a","userlist":["a","b"]}}`)
+		default:
+			fmt.Fprint(w, `{"errcode":0}`)
+		}
+	})
+
+	result, err := c.AppChat.Create(&AppChat{Name: "n", Owner: "a", Userlist: []string{"a", "b"}})
+	if err != nil || result.ChatID != "c1" {
+		t.Fatalf("got %+v, %v", result, err)
+	}
+	if path != pathAppChatCreate || body["owner"] != "a" || len(body["userlist"].([]interface{})) != 2 {
+		t.Fatalf("got %s %v", path, body)
+	}
+
+	if _, err = c.AppChat.Update(&AppChatUpdate{ChatID: "c1", AddUserList: []string{"c"}}); err != nil {
+		t.Fatal(err)
+	}
+	if path != pathAppChatUpdate || body["chatid"] != "c1" || body["name"] != nil {
+		t.Fatalf("got %s %v", path, body)
+	}
+
+	info, err := c.AppChat.Get("c1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if query != "c1" || info.ChatInfo.Owner != "a" || !reflect.DeepEqual(info.ChatInfo.Userlist, []string{"a", "b"}) {
+		t.Fatalf("got %+v", info)
+	}
+
+	msg := NewMessage(NewMarkdownContent("**x**")).ToUsers("ignored").WithSafe(1)
+	if _, err = c.AppChat.SendMessage("c1", msg); err != nil {
+		t.Fatal(err)
+	}
+	if path != pathAppChatSend || body["chatid"] != "c1" || body["msgtype"] != "markdown" || body["safe"] != float64(1) || body["touser"] != nil {
+		t.Fatalf("got %s %v", path, body)
+	}
+}
+
+func TestAppChatValidation(t *testing.T) {
+	c, _ := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request: %s", r.URL.Path)
+	})
+	creates := []*AppChat{
+		{Userlist: []string{"a"}},
+		{Userlist: make([]string, 2001)},
+		{Owner: "c", Userlist: []string{"a", "b"}},
+	}
+	for _, chat := range creates {
+		if _, err := c.AppChat.Create(chat); err == nil {
+			t.Errorf("want error for %+v", chat)
+		}
+	}
+	if _, err := c.AppChat.Update(&AppChatUpdate{Name: "n"}); err == nil {
+		t.Error("want error without chat id")
+	}
+	if _, err := c.AppChat.Update(&AppChatUpdate{ChatID: "c1", Owner: "a", DelUserList: []string{"a"}}); err == nil {
+		t.Error("want error when removing the new owner")
+	}
+	if _, err := c.AppChat.Send("", NewTextContent("x"), 0); err == nil {
+		t.Error("want error without chat id")
+	}
+	card := NewTemplateCardContent(NewTextNoticeCard("t", "", NewURLCardAction("https://a")))
+	if _, err := c.AppChat.Send("c1", card, 0); err == nil {
+		t.Error("want error for template card")
+	}
+}
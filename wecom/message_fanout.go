@@ -0,0 +1,154 @@
+// message_fanout.go 大批量接收人的应用消息发送
+// message/send 单次最多支持 1000 个 userid、100 个部门、100 个标签，超出时自动拆分为多次发送
+package wecom
+
+import (
+	"context"
+	"fmt"
+)
+
+const (
+	maxMessageToUser  = 1000
+	maxMessageToParty = 100
+	maxMessageToTag   = 100
+
+	defaultFanOutConcurrency = 4
+
+	// task_id 的最大长度
+	maxTaskIDLen = 128
+)
+
+// DeliveryReport 汇总多个批次的发送结果
+type DeliveryReport struct {
+	// 批次总数
+	Batches int
+	// 成功的批次数，接收人不合法不视为失败
+	Succeeded int
+	MsgIDs    []string
+	// 与 MsgIDs 一一对应，为各批次模板卡片的 task_id，拆分为多个批次时由 SplitMessage 生成，非模板卡片消息为空字符串
+	TaskIDs []string
+	// 仅交互型模板卡片消息返回，每个成功的批次一个，更新卡片时需要逐个使用
+	ResponseCodes   []string
+	InvalidUsers    []string
+	InvalidParties  []string
+	InvalidTags     []string
+	UnlicensedUsers []string
+	Failures        []DeliveryFailure
+}
+
+// DeliveryFailure 发送失败的批次
+type DeliveryFailure struct {
+	Message *Message
+	Err     error
+}
+
+// SplitMessage 将接收人拆分为多个符合接口限制的消息，接收人会先去重
+// task_id 在应用内必须唯一，因此拆分为多个批次时，模板卡片的 task_id 会被替换为 "task_id-批次序号"
+// 注意：同一成员可能同时属于不同批次的部门或标签，此时会收到多次
+func SplitMessage(msg *Message) []*Message {
+	users := uniqueStrings(msg.ToUser)
+	parties := uniqueStrings(msg.ToParty)
+	tags := uniqueStrings(msg.ToTag)
+	for _, id := range users {
+		if id == ToAll {
+			users = []string{ToAll}
+			break
+		}
+	}
+
+	var batches []*Message
+	for i := 0; len(users) > 0 || len(parties) > 0 || len(tags) > 0 || i == 0; i++ {
+		batch := *msg
+		batch.ToUser, users = takeStrings(users, maxMessageToUser)
+		batch.ToParty, parties = takeStrings(parties, maxMessageToParty)
+		batch.ToTag, tags = takeStrings(tags, maxMessageToTag)
+		batches = append(batches, &batch)
+	}
+	if len(batches) > 1 && msg.TemplateCard != nil && msg.TemplateCard.TaskID != "" {
+		for i, batch := range batches {
+			// 复制卡片，不修改调用方的 msg
+			card := *msg.TemplateCard
+			card.TaskID = batchTaskID(card.TaskID, i)
+			batch.TemplateCard = &card
+		}
+	}
+	return batches
+}
+
+// batchTaskID 生成第 i 个批次的 task_id，超长时截断原 task_id
+func batchTaskID(taskID string, i int) string {
+	suffix := fmt.Sprintf("-%d", i)
+	if len(taskID)+len(suffix) > maxTaskIDLen {
+		taskID = taskID[:maxTaskIDLen-len(suffix)]
+	}
+	return taskID + suffix
+}
+
+func takeStrings(s []string, n int) (head, tail []string) {
+	if len(s) <= n {
+		return s, nil
+	}
+	return s[:n], s[n:]
+}
+
+func uniqueStrings(s []string) []string {
+	seen := make(map[string]struct{}, len(s))
+	result := make([]string, 0, len(s))
+	for _, v := range s {
+		if _, ok := seen[v]; ok || v == "" {
+			continue
+		}
+		seen[v] = struct{}{}
+		result = append(result, v)
+	}
+	return result
+}
+
+// SendToMany 应用消息：向任意数量的接收人发送消息
+// 接收人按接口限制拆分为多个批次，以不超过 concurrency 的并发度发送（受 Client 限流器约束）
+// concurrency 填 0 时使用默认值；任一批次失败时返回第一个错误，report 中包含全部批次的结果
+func (m *messageService) SendToMany(ctx context.Context, msg *Message, concurrency int) (report *DeliveryReport, err error) {
+	if concurrency <= 0 {
+		concurrency = defaultFanOutConcurrency
+	}
+	s := m.WithContext(ctx)
+	batches := SplitMessage(msg)
+	results := make([]*MessageResp, len(batches))
+	errs := parallel(ctx, len(batches), concurrency, func(k int) (err error) {
+		results[k], err = s.Send(batches[k])
+		if err == nil {
+			err = checkResponse(results[k])
+		}
+		return err
+	})
+
+	report = &DeliveryReport{Batches: len(batches)}
+	for k := range batches {
+		if errs[k] != nil {
+			report.Failures = append(report.Failures, DeliveryFailure{Message: batches[k], Err: errs[k]})
+			if err == nil {
+				err = fmt.Errorf("batch %d: %w", k, errs[k])
+			}
+			// 失败时部分接收人可能仍然不合法，一并汇总
+			if results[k] == nil {
+				continue
+			}
+		} else {
+			report.Succeeded++
+			report.MsgIDs = append(report.MsgIDs, results[k].MsgID)
+			taskID := ""
+			if batches[k].TemplateCard != nil {
+				taskID = batches[k].TemplateCard.TaskID
+			}
+			report.TaskIDs = append(report.TaskIDs, taskID)
+			if results[k].ResponseCode != "" {
+				report.ResponseCodes = append(report.ResponseCodes, results[k].ResponseCode)
+			}
+		}
+		report.InvalidUsers = append(report.InvalidUsers, results[k].InvalidUsers()...)
+		report.InvalidParties = append(report.InvalidParties, results[k].InvalidParties()...)
+		report.InvalidTags = append(report.InvalidTags, results[k].InvalidTags()...)
+		report.UnlicensedUsers = append(report.UnlicensedUsers, results[k].UnlicensedUsers()...)
+	}
+	return report, err
+}
@@ -0,0 +1,94 @@
+package wecom
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+)
+
+func TestSplitMessage(t *testing.T) {
+	users := make([]string, 0, 2501)
+	for i := 0; i < 2500; i++ {
+		users = append(users, fmt.Sprint("u", i))
+	}
+	users = append(users, "u0")
+	batches := SplitMessage(NewMessage(NewTextContent("x")).ToUsers(users...).ToParties("1", "2"))
+	if len(batches) != 3 {
+		t.Fatalf("got %d batches, want 3", len(batches))
+	}
+	if len(batches[0].ToUser) != 1000 || len(batches[2].ToUser) != 500 || len(batches[0].ToParty) != 2 || len(batches[1].ToParty) != 0 {
+		t.Fatalf("unexpected batch sizes: %d %d %d", len(batches[0].ToUser), len(batches[2].ToUser), len(batches[1].ToParty))
+	}
+
+	batches = SplitMessage(NewMessage(NewTextContent("x")).ToUsers("a", ToAll, "b"))
+	if len(batches) != 1 || len(batches[0].ToUser) != 1 || batches[0].ToUser[0] != ToAll {
+		t.Fatalf("@all should be sent alone, got %v", batches[0].ToUser)
+	}
+}
+
+func TestSplitMessageTaskID(t *testing.T) {
+	users := make([]string, 1500)
+	for i := range users {
+		users[i] = fmt.Sprint("u", i)
+	}
+	card := NewButtonInteractionCard("task", "title", "desc", CardButton{Text: "ok", Key: "ok"})
+	msg := NewMessage(NewTemplateCardContent(card)).ToUsers(users...)
+	batches := SplitMessage(msg)
+	if len(batches) != 2 {
+		t.Fatalf("got %d batches, want 2", len(batches))
+	}
+	if batches[0].TemplateCard.TaskID != "task-0" || batches[1].TemplateCard.TaskID != "task-1" {
+		t.Fatalf("got task ids %s, %s", batches[0].TemplateCard.TaskID, batches[1].TemplateCard.TaskID)
+	}
+	if msg.TemplateCard.TaskID != "task" {
+		t.Fatalf("caller's message modified: %s", msg.TemplateCard.TaskID)
+	}
+
+	// 不拆分时保留原 task_id
+	if b := SplitMessage(NewMessage(NewTemplateCardContent(card)).ToUsers("a")); b[0].TemplateCard.TaskID != "task" {
+		t.Fatalf("got task id %s, want task", b[0].TemplateCard.TaskID)
+	}
+}
+
+func TestSendToMany(t *testing.T) {
+	mu := &sync.Mutex{}
+	taskIDs := make(map[string]bool)
+	c, _ := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			ToUser       string `json:"touser"`
+			TemplateCard struct {
+				TaskID string `json:"task_id"`
+			} `json:"template_card"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		mu.Lock()
+		defer mu.Unlock()
+		if taskIDs[body.TemplateCard.TaskID] {
+			fmt.Fprint(w, `{"errcode":40058,"errmsg":"duplicate task_id"}`)
+			return
+		}
+		taskIDs[body.TemplateCard.TaskID] = true
+		fmt.Fprintf(w, `{"errcode":0,"msgid":"m-%s","response_code":"rc-%s","invaliduser":"u7"}`, body.TemplateCard.TaskID, body.TemplateCard.TaskID)
+	}, NewWithAgentIDOption(1))
+
+	users := make([]string, 2100)
+	for i := range users {
+		users[i] = fmt.Sprint("u", i)
+	}
+	card := NewButtonInteractionCard("task", "title", "desc", CardButton{Text: "ok", Key: "ok"})
+	report, err := c.Message.SendToMany(context.Background(), NewMessage(NewTemplateCardContent(card)).ToUsers(users...), 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Batches != 3 || report.Succeeded != 3 || len(report.InvalidUsers) != 3 {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+	for i, taskID := range report.TaskIDs {
+		if report.MsgIDs[i] != "m-"+taskID || report.ResponseCodes[i] != "rc-"+taskID {
+			t.Fatalf("task id %s does not match msgid %s", taskID, report.MsgIDs[i])
+		}
+	}
+}
@@ -0,0 +1,244 @@
+// message_outbox.go 应用消息发件箱
+// 消息先持久化到 OutboxStore，再由后台 worker 发送；失败时按指数退避重试，超过重试次数后移入死信列表
+package wecom
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net"
+	"time"
+)
+
+const (
+	defaultOutboxMaxAttempts      = 8
+	defaultOutboxBaseBackoff      = 2 * time.Second
+	defaultOutboxMaxBackoff       = 10 * time.Minute
+	defaultOutboxFreqLimitBackoff = time.Minute
+	defaultOutboxPollInterval     = 5 * time.Second
+	defaultOutboxBatchSize        = 100
+)
+
+// 可重试的错误码，其余错误码视为永久失败，直接移入死信列表
+var outboxRetryableErrCodes = map[int]struct{}{
+	-1:                  {}, // 系统繁忙
+	errCodeAPIFreqLimit: {}, // 接口调用超过限制
+	45033:               {}, // 接口并发调用超过限制
+}
+
+// ErrDuplicateMessage 相同幂等键的消息已存在（待发送、已发送或在死信列表中）
+var ErrDuplicateMessage = errors.New("wecom: duplicate message")
+
+// ErrOutboxItemNotFound 消息不存在
+var ErrOutboxItemNotFound = errors.New("wecom: outbox item not found")
+
+// OutboxItem 发件箱中的一条消息
+type OutboxItem struct {
+	// 幂等键
+	ID            string    `json:"id"`
+	Message       *Message  `json:"message"`
+	Attempts      int       `json:"attempts"`
+	CreatedAt     time.Time `json:"created_at"`
+	NextAttemptAt time.Time `json:"next_attempt_at"`
+	LastError     string    `json:"last_error,omitempty"`
+}
+
+// OutboxStore 发件箱的持久化存储，实现需要保证并发安全
+type OutboxStore interface {
+	// Put 保存待发送消息，相同 ID 的消息已存在时返回 ErrDuplicateMessage
+	Put(item *OutboxItem) error
+	// Due 返回 NextAttemptAt 不晚于 now 的待发送消息，最多 limit 条
+	Due(now time.Time, limit int) ([]*OutboxItem, error)
+	// Update 更新待发送消息的重试信息
+	Update(item *OutboxItem) error
+	// Done 将消息标记为已发送，其 ID 仍用于去重
+	Done(id string) error
+	// Dead 将待发送消息移入死信列表
+	Dead(item *OutboxItem) error
+	// DeadLetters 返回死信列表
+	DeadLetters() ([]*OutboxItem, error)
+	// Requeue 将死信重新放回待发送列表
+	Requeue(id string) error
+	// RemoveDead 从死信列表中删除
+	RemoveDead(id string) error
+}
+
+// Outbox 发件箱，Enqueue 可在任意 goroutine 中调用，Run 启动发送 worker
+type Outbox struct {
+	service *messageService
+	store   OutboxStore
+
+	// 最大发送次数，超过后移入死信列表
+	MaxAttempts int
+	// 第一次重试的等待时间，之后每次翻倍，不超过 MaxBackoff
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+	// 遇到 45009 接口调用超过限制时的最小等待时间
+	FreqLimitBackoff time.Duration
+	// 没有待发送消息时的轮询间隔
+	PollInterval time.Duration
+	// 消息移入死信列表时回调，可以为 nil
+	OnDead func(item *OutboxItem)
+	// Run 处理消息时出现存储错误等错误时回调，Run 会在 PollInterval 后继续处理，可以为 nil
+	OnError func(err error)
+
+	wake chan struct{}
+}
+
+// NewOutbox 创建发件箱，store 可以使用 NewFileOutboxStore
+func (m *messageService) NewOutbox(store OutboxStore) *Outbox {
+	return &Outbox{
+		service:          &messageService{client: m.client},
+		store:            store,
+		MaxAttempts:      defaultOutboxMaxAttempts,
+		BaseBackoff:      defaultOutboxBaseBackoff,
+		MaxBackoff:       defaultOutboxMaxBackoff,
+		FreqLimitBackoff: defaultOutboxFreqLimitBackoff,
+		PollInterval:     defaultOutboxPollInterval,
+		wake:             make(chan struct{}, 1),
+	}
+}
+
+// Enqueue 将消息放入发件箱，key 为幂等键，为空时随机生成
+// 相同 key 的消息只会发送一次，重复放入时返回 ErrDuplicateMessage
+func (o *Outbox) Enqueue(key string, msg *Message) (id string, err error) {
+	if key == "" {
+		if key, err = randomID(); err != nil {
+			return "", err
+		}
+	}
+	now := time.Now()
+	item := &OutboxItem{ID: key, Message: msg, CreatedAt: now, NextAttemptAt: now}
+	if err = o.store.Put(item); err != nil {
+		return "", err
+	}
+	select {
+	case o.wake <- struct{}{}:
+	default:
+	}
+	return key, nil
+}
+
+func randomID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// Run 持续发送到期的消息，直到 ctx 被取消
+func (o *Outbox) Run(ctx context.Context) error {
+	for {
+		n, err := o.ProcessOnce(ctx)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil && o.OnError != nil {
+			o.OnError(err)
+		}
+		if err == nil && n > 0 {
+			continue // 可能还有到期的消息
+		}
+		timer := time.NewTimer(o.PollInterval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-o.wake:
+			timer.Stop()
+		case <-timer.C:
+		}
+	}
+}
+
+// ProcessOnce 发送一批到期的消息，返回处理的消息数
+func (o *Outbox) ProcessOnce(ctx context.Context) (int, error) {
+	items, err := o.store.Due(time.Now(), defaultOutboxBatchSize)
+	if err != nil {
+		return 0, err
+	}
+	s := o.service.WithContext(ctx)
+	for k, item := range items {
+		if ctx.Err() != nil {
+			return k, ctx.Err()
+		}
+		if err = o.deliver(s, item); err != nil {
+			return k, err
+		}
+	}
+	return len(items), nil
+}
+
+// deliver 发送一条消息，返回的 err 仅表示存储出错
+func (o *Outbox) deliver(s *messageService, item *OutboxItem) error {
+	item.Attempts++
+	result, err := s.Send(item.Message)
+	if err == nil {
+		err = checkResponse(result)
+	}
+	if err == nil {
+		return o.store.Done(item.ID)
+	}
+	if s.ctx != nil && s.ctx.Err() != nil {
+		// 因 ctx 取消导致的失败不计入重试次数
+		item.Attempts--
+		return nil
+	}
+
+	item.LastError = err.Error()
+	if !isRetryable(err) || item.Attempts >= o.MaxAttempts {
+		if err = o.store.Dead(item); err != nil {
+			return err
+		}
+		if o.OnDead != nil {
+			o.OnDead(item)
+		}
+		return nil
+	}
+	backoff := o.BaseBackoff << uint(item.Attempts-1)
+	if backoff > o.MaxBackoff || backoff <= 0 {
+		backoff = o.MaxBackoff
+	}
+	if isFreqLimitErr(err) && backoff < o.FreqLimitBackoff {
+		backoff = o.FreqLimitBackoff
+	}
+	item.NextAttemptAt = time.Now().Add(backoff)
+	return o.store.Update(item)
+}
+
+// isRetryable 网络错误可重试，业务错误仅部分错误码可重试
+// 消息校验失败等其他错误重试也不会成功，直接移入死信列表
+func isRetryable(err error) bool {
+	var e *Error
+	if errors.As(err, &e) {
+		_, ok := outboxRetryableErrCodes[e.ErrCode]
+		return ok
+	}
+	var ne net.Error
+	return errors.As(err, &ne) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF)
+}
+
+// DeadLetters 返回死信列表
+func (o *Outbox) DeadLetters() ([]*OutboxItem, error) {
+	return o.store.DeadLetters()
+}
+
+// Requeue 将死信重新放回待发送列表，重试次数清零
+func (o *Outbox) Requeue(id string) error {
+	if err := o.store.Requeue(id); err != nil {
+		return err
+	}
+	select {
+	case o.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// RemoveDead 从死信列表中删除
+func (o *Outbox) RemoveDead(id string) error {
+	return o.store.RemoveDead(id)
+}
@@ -0,0 +1,303 @@
+// message_outbox_store.go 基于本地文件的 OutboxStore 实现
+package wecom
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	outboxPendingDir = "pending"
+	outboxDeadDir    = "dead"
+	outboxSentDir    = "sent"
+	outboxCorruptDir = "corrupt"
+)
+
+// FileOutboxStore 将每条消息保存为一个 JSON 文件，适用于单实例部署
+// 目录结构：pending/ 待发送，dead/ 死信，sent/ 已发送（仅用于去重），corrupt/ 无法读取的文件
+// 待发送消息的发送时间保存在内存索引中，Due 只读取到期的文件，运行期间不要在外部修改 pending/ 目录
+type FileOutboxStore struct {
+	dir string
+	mu  sync.Mutex
+	// 待发送消息的索引，第一次调用 Due 时从 pending/ 目录建立，nil 表示尚未建立
+	index map[string]outboxIndexEntry
+
+	// 发现无法读取或解析的文件时回调，该文件已被移入 corrupt/ 目录，可以为 nil
+	OnCorrupt func(path string, err error)
+}
+
+type outboxIndexEntry struct {
+	createdAt     time.Time
+	nextAttemptAt time.Time
+}
+
+// NewFileOutboxStore 使用 dir 作为存储目录，目录不存在时自动创建
+func NewFileOutboxStore(dir string) (*FileOutboxStore, error) {
+	for _, sub := range []string{outboxPendingDir, outboxDeadDir, outboxSentDir, outboxCorruptDir} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0755); err != nil {
+			return nil, err
+		}
+	}
+	return &FileOutboxStore{dir: dir}, nil
+}
+
+// 幂等键可能包含任意字符，使用哈希作为文件名
+func (s *FileOutboxStore) path(sub, id string) string {
+	sum := sha1.Sum([]byte(id))
+	return filepath.Join(s.dir, sub, hex.EncodeToString(sum[:])+".json")
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// writeFileAtomic 先写临时文件再重命名，避免写入一半时进程退出导致文件损坏
+func writeFileAtomic(path string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err = ioutil.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func readItem(path string) (*OutboxItem, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	item := new(OutboxItem)
+	if err = json.Unmarshal(data, item); err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+func (s *FileOutboxStore) readDir(sub string) ([]*OutboxItem, error) {
+	files, err := filepath.Glob(filepath.Join(s.dir, sub, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	items := make([]*OutboxItem, 0, len(files))
+	for _, f := range files {
+		item, err := readItem(f)
+		if err != nil {
+			// 单个文件损坏不影响其他消息，移入 corrupt/ 后跳过
+			s.quarantine(f, err)
+			continue
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// quarantine 将无法读取的文件移入 corrupt/ 目录，并通过 OnCorrupt 回调通知
+func (s *FileOutboxStore) quarantine(path string, err error) {
+	dst := filepath.Join(s.dir, outboxCorruptDir, filepath.Base(filepath.Dir(path))+"-"+filepath.Base(path))
+	if rerr := os.Rename(path, dst); rerr != nil {
+		err = fmt.Errorf("%v, quarantine: %v", err, rerr)
+	}
+	if s.OnCorrupt != nil {
+		s.OnCorrupt(path, err)
+	}
+}
+
+// setIndex 更新索引中的待发送消息，索引尚未建立时不做处理
+func (s *FileOutboxStore) setIndex(item *OutboxItem) {
+	if s.index != nil {
+		s.index[item.ID] = outboxIndexEntry{createdAt: item.CreatedAt, nextAttemptAt: item.NextAttemptAt}
+	}
+}
+
+// loadIndex 读取 pending/ 目录建立索引，仅在第一次调用时读取
+func (s *FileOutboxStore) loadIndex() error {
+	if s.index != nil {
+		return nil
+	}
+	items, err := s.readDir(outboxPendingDir)
+	if err != nil {
+		return err
+	}
+	s.index = make(map[string]outboxIndexEntry, len(items))
+	for _, item := range items {
+		// sent/ 中已有去重记录说明该消息已经发送过（例如文件被手动放回 pending/），不再发送
+		if fileExists(s.path(outboxSentDir, item.ID)) {
+			_ = os.Remove(s.path(outboxPendingDir, item.ID))
+			continue
+		}
+		s.setIndex(item)
+	}
+	return nil
+}
+
+func (s *FileOutboxStore) Put(item *OutboxItem) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, sub := range []string{outboxPendingDir, outboxDeadDir, outboxSentDir} {
+		if fileExists(s.path(sub, item.ID)) {
+			return ErrDuplicateMessage
+		}
+	}
+	if err := writeFileAtomic(s.path(outboxPendingDir, item.ID), item); err != nil {
+		return err
+	}
+	s.setIndex(item)
+	return nil
+}
+
+func (s *FileOutboxStore) Due(now time.Time, limit int) ([]*OutboxItem, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.loadIndex(); err != nil {
+		return nil, err
+	}
+	var ids []string
+	for id, entry := range s.index {
+		if !entry.nextAttemptAt.After(now) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return s.index[ids[i]].createdAt.Before(s.index[ids[j]].createdAt)
+	})
+
+	due := make([]*OutboxItem, 0, len(ids))
+	for _, id := range ids {
+		if len(due) >= limit {
+			break
+		}
+		path := s.path(outboxPendingDir, id)
+		item, err := readItem(path)
+		if err != nil {
+			delete(s.index, id)
+			if !os.IsNotExist(err) {
+				s.quarantine(path, err)
+			}
+			continue
+		}
+		due = append(due, item)
+	}
+	return due, nil
+}
+
+func (s *FileOutboxStore) Update(item *OutboxItem) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	path := s.path(outboxPendingDir, item.ID)
+	if !fileExists(path) {
+		return ErrOutboxItemNotFound
+	}
+	if err := writeFileAtomic(path, item); err != nil {
+		return err
+	}
+	s.setIndex(item)
+	return nil
+}
+
+func (s *FileOutboxStore) Done(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// 通过重命名原子地从 pending/ 移入 sent/，避免进程在两步之间退出导致重复发送
+	sent := s.path(outboxSentDir, id)
+	if err := os.Rename(s.path(outboxPendingDir, id), sent); err != nil {
+		if os.IsNotExist(err) {
+			return ErrOutboxItemNotFound
+		}
+		return err
+	}
+	delete(s.index, id)
+	// 已发送的消息只保留发送时间，用于去重；失败时保留完整内容，不影响去重
+	_ = writeFileAtomic(sent, &OutboxItem{ID: id, CreatedAt: time.Now()})
+	return nil
+}
+
+func (s *FileOutboxStore) Dead(item *OutboxItem) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// 先更新 pending/ 中的文件再重命名，保证消息只会出现在一个目录中
+	pending := s.path(outboxPendingDir, item.ID)
+	if err := writeFileAtomic(pending, item); err != nil {
+		return err
+	}
+	if err := os.Rename(pending, s.path(outboxDeadDir, item.ID)); err != nil {
+		return err
+	}
+	delete(s.index, item.ID)
+	return nil
+}
+
+func (s *FileOutboxStore) DeadLetters() ([]*OutboxItem, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	items, err := s.readDir(outboxDeadDir)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].CreatedAt.Before(items[j].CreatedAt)
+	})
+	return items, nil
+}
+
+func (s *FileOutboxStore) Requeue(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	path := s.path(outboxDeadDir, id)
+	item, err := readItem(path)
+	if os.IsNotExist(err) {
+		return ErrOutboxItemNotFound
+	}
+	if err != nil {
+		return err
+	}
+	item.Attempts = 0
+	item.NextAttemptAt = time.Now()
+	if err = writeFileAtomic(path, item); err != nil {
+		return err
+	}
+	if err = os.Rename(path, s.path(outboxPendingDir, id)); err != nil {
+		return err
+	}
+	s.setIndex(item)
+	return nil
+}
+
+func (s *FileOutboxStore) RemoveDead(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	err := os.Remove(s.path(outboxDeadDir, id))
+	if os.IsNotExist(err) {
+		return ErrOutboxItemNotFound
+	}
+	return err
+}
+
+// PurgeSent 删除 before 之前发送的去重记录，之后相同幂等键的消息可以再次放入
+func (s *FileOutboxStore) PurgeSent(before time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	items, err := s.readDir(outboxSentDir)
+	if err != nil {
+		return err
+	}
+	for _, item := range items {
+		if item.CreatedAt.Before(before) {
+			if err = os.Remove(s.path(outboxSentDir, item.ID)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
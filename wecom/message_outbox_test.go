@@ -0,0 +1,239 @@
+package wecom
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newOutboxTestClient(t *testing.T, sent *int32) *Client {
+	c, _ := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(sent, 1)
+		fmt.Fprint(w, `{"errcode":0,"msgid":"m"}`)
+	}, NewWithAgentIDOption(1))
+	return c
+}
+
+func newTestFileOutboxStore(t *testing.T) *FileOutboxStore {
+	dir, err := ioutil.TempDir("", "outbox")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.RemoveAll(dir) })
+	store, err := NewFileOutboxStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+func TestOutboxDeliverAndDedup(t *testing.T) {
+	var sent int32
+	store := newTestFileOutboxStore(t)
+	outbox := newOutboxTestClient(t, &sent).Message.NewOutbox(store)
+	msg := NewMessage(NewTextContent("x")).ToUsers("u")
+
+	if _, err := outbox.Enqueue("k1", msg); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := outbox.Enqueue("k1", msg); err != ErrDuplicateMessage {
+		t.Fatalf("got %v, want ErrDuplicateMessage", err)
+	}
+	if n, err := outbox.ProcessOnce(context.Background()); err != nil || n != 1 {
+		t.Fatalf("got %d, %v", n, err)
+	}
+	if _, err := outbox.Enqueue("k1", msg); err != ErrDuplicateMessage {
+		t.Fatalf("got %v after sent, want ErrDuplicateMessage", err)
+	}
+	if n, _ := outbox.ProcessOnce(context.Background()); n != 0 || sent != 1 {
+		t.Fatalf("got %d due and %d sent, want 0 and 1", n, sent)
+	}
+}
+
+func TestFileOutboxStoreCorruptFile(t *testing.T) {
+	var sent int32
+	store := newTestFileOutboxStore(t)
+	var corrupt []string
+	store.OnCorrupt = func(path string, err error) { corrupt = append(corrupt, path) }
+	outbox := newOutboxTestClient(t, &sent).Message.NewOutbox(store)
+	if _, err := outbox.Enqueue("k1", NewMessage(NewTextContent("x")).ToUsers("u")); err != nil {
+		t.Fatal(err)
+	}
+	bad := filepath.Join(store.dir, outboxPendingDir, "bad.json")
+	if err := ioutil.WriteFile(bad, []byte("{not json"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := outbox.ProcessOnce(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if sent != 1 || len(corrupt) != 1 || corrupt[0] != bad {
+		t.Fatalf("got %d sent and corrupt files %v", sent, corrupt)
+	}
+	if fileExists(bad) || !fileExists(filepath.Join(store.dir, outboxCorruptDir, "pending-bad.json")) {
+		t.Fatal("corrupt file not quarantined")
+	}
+}
+
+func TestFileOutboxStoreSkipsSent(t *testing.T) {
+	store := newTestFileOutboxStore(t)
+	item := &OutboxItem{ID: "k1", Message: NewMessage(NewTextContent("x")), CreatedAt: time.Now()}
+	if err := store.Put(item); err != nil {
+		t.Fatal(err)
+	}
+	// 模拟已发送消息的文件被放回 pending/
+	if err := writeFileAtomic(store.path(outboxSentDir, "k1"), item); err != nil {
+		t.Fatal(err)
+	}
+	due, err := store.Due(time.Now(), 10)
+	if err != nil || len(due) != 0 {
+		t.Fatalf("got %d due, %v; want 0", len(due), err)
+	}
+	if fileExists(store.path(outboxPendingDir, "k1")) {
+		t.Fatal("pending file of a sent message not removed")
+	}
+}
+
+func TestFileOutboxStoreDeadAndRequeue(t *testing.T) {
+	store := newTestFileOutboxStore(t)
+	item := &OutboxItem{ID: "k1", Message: NewMessage(NewTextContent("x")), CreatedAt: time.Now()}
+	if err := store.Put(item); err != nil {
+		t.Fatal(err)
+	}
+	item.Attempts = 3
+	item.LastError = "boom"
+	if err := store.Dead(item); err != nil {
+		t.Fatal(err)
+	}
+	dead, err := store.DeadLetters()
+	if err != nil || len(dead) != 1 || dead[0].LastError != "boom" {
+		t.Fatalf("got %+v, %v", dead, err)
+	}
+	if due, _ := store.Due(time.Now(), 10); len(due) != 0 {
+		t.Fatal("dead message is still due")
+	}
+	if err = store.Requeue("k1"); err != nil {
+		t.Fatal(err)
+	}
+	due, _ := store.Due(time.Now(), 10)
+	if len(due) != 1 || due[0].Attempts != 0 {
+		t.Fatalf("got %+v after requeue", due)
+	}
+	if dead, _ = store.DeadLetters(); len(dead) != 0 {
+		t.Fatal("requeued message is still dead")
+	}
+}
+
+func TestFileOutboxStoreDueReadsOnlyDueFiles(t *testing.T) {
+	store := newTestFileOutboxStore(t)
+	var corrupt []string
+	store.OnCorrupt = func(path string, err error) { corrupt = append(corrupt, path) }
+	now := time.Now()
+	later := &OutboxItem{ID: "later", Message: NewMessage(NewTextContent("x")), CreatedAt: now, NextAttemptAt: now.Add(time.Hour)}
+	if err := store.Put(later); err != nil {
+		t.Fatal(err)
+	}
+	if due, err := store.Due(now, 10); err != nil || len(due) != 0 {
+		t.Fatalf("got %d due, %v; want 0", len(due), err)
+	}
+	// 未到期的文件不会被读取
+	if err := ioutil.WriteFile(store.path(outboxPendingDir, "later"), []byte("{not json"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	first := &OutboxItem{ID: "first", Message: NewMessage(NewTextContent("x")), CreatedAt: now.Add(-time.Minute), NextAttemptAt: now}
+	second := &OutboxItem{ID: "second", Message: NewMessage(NewTextContent("x")), CreatedAt: now, NextAttemptAt: now}
+	for _, item := range []*OutboxItem{second, first} {
+		if err := store.Put(item); err != nil {
+			t.Fatal(err)
+		}
+	}
+	due, err := store.Due(now, 10)
+	if err != nil || len(due) != 2 || due[0].ID != "first" || due[1].ID != "second" {
+		t.Fatalf("got %+v, %v", due, err)
+	}
+	if len(corrupt) != 0 {
+		t.Fatalf("read files that are not due: %v", corrupt)
+	}
+
+	// 重新打开时从 pending/ 目录建立索引
+	reopened, err := NewFileOutboxStore(store.dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reopened.OnCorrupt = store.OnCorrupt
+	if due, err = reopened.Due(now, 1); err != nil || len(due) != 1 || due[0].ID != "first" {
+		t.Fatalf("got %+v, %v after reopen", due, err)
+	}
+}
+
+func TestOutboxDeadLettersValidationErrors(t *testing.T) {
+	var sent int32
+	store := newTestFileOutboxStore(t)
+	outbox := newOutboxTestClient(t, &sent).Message.NewOutbox(store)
+	if _, err := outbox.Enqueue("k1", NewMessage(NewTextContent("x"))); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := outbox.ProcessOnce(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	dead, err := outbox.DeadLetters()
+	if err != nil || len(dead) != 1 || dead[0].Attempts != 1 {
+		t.Fatalf("got %+v, %v; want the message dead after one attempt", dead, err)
+	}
+	if sent != 0 {
+		t.Fatalf("got %d requests, want 0", sent)
+	}
+}
+
+func TestIsRetryable(t *testing.T) {
+	cases := []struct {
+		err  error
+		want bool
+	}{
+		{&Error{ErrCode: -1}, true},
+		{&Error{ErrCode: errCodeAPIFreqLimit}, true},
+		{&Error{ErrCode: 40003}, false},
+		{&url.Error{Op: "Post", URL: "http://x", Err: errors.New("connection refused")}, true},
+		{io.ErrUnexpectedEOF, true},
+		{errors.New("touser, toparty and totag cannot be empty at the same time"), false},
+	}
+	for _, c := range cases {
+		if got := isRetryable(c.err); got != c.want {
+			t.Errorf("isRetryable(%v) = %v, want %v", c.err, got, c.want)
+		}
+	}
+}
+
+type failingOutboxStore struct {
+	OutboxStore
+}
+
+func (failingOutboxStore) Due(time.Time, int) ([]*OutboxItem, error) {
+	return nil, errors.New("disk error")
+}
+
+func TestOutboxRunReportsErrors(t *testing.T) {
+	outbox := (&messageService{}).NewOutbox(failingOutboxStore{})
+	outbox.PollInterval = time.Millisecond
+	ctx, cancel := context.WithCancel(context.Background())
+	var errs int32
+	outbox.OnError = func(err error) {
+		if atomic.AddInt32(&errs, 1) == 2 {
+			cancel()
+		}
+	}
+	if err := outbox.Run(ctx); err != context.Canceled {
+		t.Fatalf("got %v, want context.Canceled", err)
+	}
+}
@@ -1,2 +1,474 @@
-// basic.go 对应的是 https://work.weixin.qq.com/api/doc/90000/90135/90235 文档内容
+// message_push.go 对应的是 https://developer.work.weixin.qq.com/document/path/90235 文档内容
+// 主要实现了发送应用消息的 API
 package wecom
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+const (
+	pathMessageSend       = "/cgi-bin/message/send"
+	pathMessageRecall     = "/cgi-bin/message/recall"
+	pathMessageUpdateCard = "/cgi-bin/message/update_template_card"
+
+	// 发送给应用可见范围内的全部成员
+	ToAll = "@all"
+)
+
+type messageService service
+
+func (m *messageService) WithContext(ctx context.Context) *messageService {
+	return &messageService{
+		client: m.client,
+		ctx:    ctx,
+	}
+}
+
+// 消息类型
+type MsgType string
+
+const (
+	MsgTypeText              MsgType = "text"
+	MsgTypeImage             MsgType = "image"
+	MsgTypeVoice             MsgType = "voice"
+	MsgTypeVideo             MsgType = "video"
+	MsgTypeFile              MsgType = "file"
+	MsgTypeTextCard          MsgType = "textcard"
+	MsgTypeNews              MsgType = "news"
+	MsgTypeMpNews            MsgType = "mpnews"
+	MsgTypeMarkdown          MsgType = "markdown"
+	MsgTypeMiniprogramNotice MsgType = "miniprogram_notice"
+	MsgTypeTemplateCard      MsgType = "template_card"
+)
+
+// MessageContent 消息内容，应用消息与群聊消息共用，根据 MsgType 只有对应的字段有效
+type MessageContent struct {
+	MsgType           MsgType               `json:"msgtype"`
+	Text              *TextMsg              `json:"text,omitempty"`
+	Image             *MediaMsg             `json:"image,omitempty"`
+	Voice             *MediaMsg             `json:"voice,omitempty"`
+	Video             *VideoMsg             `json:"video,omitempty"`
+	File              *MediaMsg             `json:"file,omitempty"`
+	TextCard          *TextCardMsg          `json:"textcard,omitempty"`
+	News              *NewsMsg              `json:"news,omitempty"`
+	MpNews            *MpNewsMsg            `json:"mpnews,omitempty"`
+	Markdown          *MarkdownMsg          `json:"markdown,omitempty"`
+	MiniprogramNotice *MiniprogramNoticeMsg `json:"miniprogram_notice,omitempty"`
+	TemplateCard      *TemplateCard         `json:"template_card,omitempty"`
+}
+
+type TextMsg struct {
+	Content string `json:"content"`
+}
+
+// 图片、语音、文件消息
+type MediaMsg struct {
+	MediaID string `json:"media_id"`
+}
+
+type VideoMsg struct {
+	MediaID     string `json:"media_id"`
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+type TextCardMsg struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	URL         string `json:"url"`
+	BtnTxt      string `json:"btntxt,omitempty"`
+}
+
+type NewsMsg struct {
+	Articles []NewsArticle `json:"articles"`
+}
+
+// 图文消息的文章，url 与 appid/pagepath 二选一
+type NewsArticle struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	URL         string `json:"url,omitempty"`
+	PicURL      string `json:"picurl,omitempty"`
+	Appid       string `json:"appid,omitempty"`
+	Pagepath    string `json:"pagepath,omitempty"`
+}
+
+type MpNewsMsg struct {
+	Articles []MpNewsArticle `json:"articles"`
+}
+
+type MpNewsArticle struct {
+	Title            string `json:"title"`
+	ThumbMediaID     string `json:"thumb_media_id"`
+	Author           string `json:"author,omitempty"`
+	ContentSourceURL string `json:"content_source_url,omitempty"`
+	Content          string `json:"content"`
+	Digest           string `json:"digest,omitempty"`
+}
+
+type MarkdownMsg struct {
+	Content string `json:"content"`
+}
+
+type MiniprogramNoticeMsg struct {
+	Appid             string            `json:"appid"`
+	Page              string            `json:"page,omitempty"`
+	Title             string            `json:"title"`
+	Description       string            `json:"description,omitempty"`
+	EmphasisFirstItem bool              `json:"emphasis_first_item,omitempty"`
+	ContentItem       []KeyValueContent `json:"content_item,omitempty"`
+}
+
+type KeyValueContent struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// NewTextContent 文本消息
+func NewTextContent(content string) *MessageContent {
+	return &MessageContent{MsgType: MsgTypeText, Text: &TextMsg{Content: content}}
+}
+
+// NewImageContent 图片消息
+func NewImageContent(mediaID string) *MessageContent {
+	return &MessageContent{MsgType: MsgTypeImage, Image: &MediaMsg{MediaID: mediaID}}
+}
+
+// NewVoiceContent 语音消息
+func NewVoiceContent(mediaID string) *MessageContent {
+	return &MessageContent{MsgType: MsgTypeVoice, Voice: &MediaMsg{MediaID: mediaID}}
+}
+
+// NewVideoContent 视频消息
+func NewVideoContent(mediaID, title, description string) *MessageContent {
+	return &MessageContent{MsgType: MsgTypeVideo, Video: &VideoMsg{MediaID: mediaID, Title: title, Description: description}}
+}
+
+// NewFileContent 文件消息
+func NewFileContent(mediaID string) *MessageContent {
+	return &MessageContent{MsgType: MsgTypeFile, File: &MediaMsg{MediaID: mediaID}}
+}
+
+// NewTextCardContent 文本卡片消息，btnTxt 为空时默认为“详情”
+func NewTextCardContent(title, description, url, btnTxt string) *MessageContent {
+	return &MessageContent{MsgType: MsgTypeTextCard, TextCard: &TextCardMsg{Title: title, Description: description, URL: url, BtnTxt: btnTxt}}
+}
+
+// NewNewsContent 图文消息，最多 8 条
+func NewNewsContent(articles ...NewsArticle) *MessageContent {
+	return &MessageContent{MsgType: MsgTypeNews, News: &NewsMsg{Articles: articles}}
+}
+
+// NewMpNewsContent 图文消息（mpnews），最多 8 条
+func NewMpNewsContent(articles ...MpNewsArticle) *MessageContent {
+	return &MessageContent{MsgType: MsgTypeMpNews, MpNews: &MpNewsMsg{Articles: articles}}
+}
+
+// NewMarkdownContent markdown 消息
+func NewMarkdownContent(content string) *MessageContent {
+	return &MessageContent{MsgType: MsgTypeMarkdown, Markdown: &MarkdownMsg{Content: content}}
+}
+
+// NewMiniprogramNoticeContent 小程序通知消息
+func NewMiniprogramNoticeContent(notice *MiniprogramNoticeMsg) *MessageContent {
+	return &MessageContent{MsgType: MsgTypeMiniprogramNotice, MiniprogramNotice: notice}
+}
+
+// NewTemplateCardContent 模板卡片消息
+func NewTemplateCardContent(card *TemplateCard) *MessageContent {
+	return &MessageContent{MsgType: MsgTypeTemplateCard, TemplateCard: card}
+}
+
+// Message 应用消息
+// touser、toparty、totag 不能同时为空
+type Message struct {
+	ToUser  []string `json:"-"`
+	ToParty []string `json:"-"`
+	ToTag   []string `json:"-"`
+	// 为 0 时使用 NewWithAgentIDOption 设置的应用 ID
+	AgentID int `json:"agentid"`
+	MessageContent
+	// 是否是保密消息，0 表示可对外分享，1 表示不能分享且内容显示水印，2 表示仅限在企业内分享
+	Safe int `json:"safe,omitempty"`
+	// 是否开启 id 转译
+	EnableIDTrans int `json:"enable_id_trans,omitempty"`
+	// 是否开启重复消息检查
+	EnableDuplicateCheck int `json:"enable_duplicate_check,omitempty"`
+	// 重复消息检查的时间间隔，单位为秒，默认 1800，最大不超过 4 小时
+	DuplicateCheckInterval int `json:"duplicate_check_interval,omitempty"`
+}
+
+// NewMessage 使用 content 创建应用消息
+func NewMessage(content *MessageContent) *Message {
+	return &Message{MessageContent: *content}
+}
+
+// ToUsers 添加接收成员，"@all" 表示应用可见范围内的全部成员
+func (m *Message) ToUsers(userIDs ...string) *Message {
+	m.ToUser = append(m.ToUser, userIDs...)
+	return m
+}
+
+// ToParties 添加接收部门
+func (m *Message) ToParties(partyIDs ...string) *Message {
+	m.ToParty = append(m.ToParty, partyIDs...)
+	return m
+}
+
+// ToTags 添加接收标签
+func (m *Message) ToTags(tagIDs ...string) *Message {
+	m.ToTag = append(m.ToTag, tagIDs...)
+	return m
+}
+
+// ToAllUsers 发送给应用可见范围内的全部成员
+func (m *Message) ToAllUsers() *Message {
+	m.ToUser = []string{ToAll}
+	return m
+}
+
+// WithAgentID 指定应用 ID
+func (m *Message) WithAgentID(agentID int) *Message {
+	m.AgentID = agentID
+	return m
+}
+
+// WithSafe 设置保密消息
+func (m *Message) WithSafe(safe int) *Message {
+	m.Safe = safe
+	return m
+}
+
+// WithIDTrans 开启 id 转译
+func (m *Message) WithIDTrans() *Message {
+	m.EnableIDTrans = 1
+	return m
+}
+
+// WithDuplicateCheck 开启重复消息检查，interval 为检查的时间间隔，单位为秒，为 0 时使用默认值
+func (m *Message) WithDuplicateCheck(interval int) *Message {
+	m.EnableDuplicateCheck = 1
+	m.DuplicateCheckInterval = interval
+	return m
+}
+
+// MarshalJSON 将接收人列表转换为以 | 分隔的字符串
+func (m Message) MarshalJSON() ([]byte, error) {
+	type alias Message
+	return json.Marshal(&struct {
+		ToUser  string `json:"touser,omitempty"`
+		ToParty string `json:"toparty,omitempty"`
+		ToTag   string `json:"totag,omitempty"`
+		alias
+	}{
+		ToUser:  strings.Join(m.ToUser, "|"),
+		ToParty: strings.Join(m.ToParty, "|"),
+		ToTag:   strings.Join(m.ToTag, "|"),
+		alias:   alias(m),
+	})
+}
+
+// UnmarshalJSON 与 MarshalJSON 对应
+func (m *Message) UnmarshalJSON(data []byte) error {
+	type alias Message
+	v := &struct {
+		ToUser  string `json:"touser"`
+		ToParty string `json:"toparty"`
+		ToTag   string `json:"totag"`
+		*alias
+	}{alias: (*alias)(m)}
+	if err := json.Unmarshal(data, v); err != nil {
+		return err
+	}
+	m.ToUser = splitRecipients(v.ToUser)
+	m.ToParty = splitRecipients(v.ToParty)
+	m.ToTag = splitRecipients(v.ToTag)
+	return nil
+}
+
+func splitRecipients(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, "|")
+}
+
+type MessageResp struct {
+	baseResponse
+	InvalidUser    string `json:"invaliduser,omitempty"`
+	InvalidParty   string `json:"invalidparty,omitempty"`
+	InvalidTag     string `json:"invalidtag,omitempty"`
+	UnlicensedUser string `json:"unlicenseduser,omitempty"`
+	MsgID          string `json:"msgid,omitempty"`
+	// 仅消息类型为按钮交互型、投票选择型和多项选择型的模板卡片消息返回，用于更新卡片，72 小时内有效且只能使用一次
+	ResponseCode string `json:"response_code,omitempty"`
+}
+
+// InvalidUsers 不合法的 userid
+func (r *MessageResp) InvalidUsers() []string {
+	return splitRecipients(r.InvalidUser)
+}
+
+// InvalidParties 不合法的部门 ID
+func (r *MessageResp) InvalidParties() []string {
+	return splitRecipients(r.InvalidParty)
+}
+
+// InvalidTags 不合法的标签 ID
+func (r *MessageResp) InvalidTags() []string {
+	return splitRecipients(r.InvalidTag)
+}
+
+// UnlicensedUsers 没有基础接口许可的 userid
+func (r *MessageResp) UnlicensedUsers() []string {
+	return splitRecipients(r.UnlicensedUser)
+}
+
+// Send 应用消息：发送应用消息
+// 参考链接：https://developer.work.weixin.qq.com/document/path/90236
+func (m *messageService) Send(msg *Message) (result *MessageResp, err error) {
+	if len(msg.ToUser) == 0 && len(msg.ToParty) == 0 && len(msg.ToTag) == 0 {
+		return nil, errors.New("touser, toparty and totag cannot be empty at the same time")
+	}
+	if msg.AgentID == 0 {
+		if m.client.agentID == 0 {
+			return nil, errors.New("agent id is required")
+		}
+		// 不修改调用方的 msg
+		c := *msg
+		c.AgentID = m.client.agentID
+		msg = &c
+	}
+	if msg.TemplateCard != nil {
+		if err = msg.TemplateCard.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	failCount := -1
+	// 默认尝试一次，即不进行失败重试
+	for failCount < m.client.maxRetryTimes {
+		failCount++
+		var req *http.Request
+		req, err = m.client.newRequest(http.MethodPost, pathMessageSend, msg)
+		if err != nil {
+			continue // 如果循环结束，则会返回该 err
+		}
+		result = new(MessageResp)
+		err = (*service)(m).doRequest(req, result)
+		if err != nil {
+			continue // 如果循环结束，则会返回该 err
+		}
+		// 成功，return
+		return result, nil
+	}
+	// 失败，返回最后一次请求的 err
+	return nil, err
+}
+
+type recallMessage struct {
+	MsgID string `json:"msgid"`
+}
+
+// Recall 应用消息：撤回 24 小时内发送的应用消息
+// 参考链接：https://developer.work.weixin.qq.com/document/path/94867
+func (m *messageService) Recall(msgID string) (result *MessageResp, err error) {
+	failCount := -1
+	// 默认尝试一次，即不进行失败重试
+	for failCount < m.client.maxRetryTimes {
+		failCount++
+		body := recallMessage{MsgID: msgID}
+		var req *http.Request
+		req, err = m.client.newRequest(http.MethodPost, pathMessageRecall, body)
+		if err != nil {
+			continue // 如果循环结束，则会返回该 err
+		}
+		result = new(MessageResp)
+		err = (*service)(m).doRequest(req, result)
+		if err != nil {
+			continue // 如果循环结束，则会返回该 err
+		}
+		// 成功，return
+		return result, nil
+	}
+	// 失败，返回最后一次请求的 err
+	return nil, err
+}
+
+// TemplateCardUpdate 更新模板卡片
+// 更新范围 userids、partyids、tagids 均为空且 AtAll 为 0 时，更新发送时的全部接收人
+// Button 与 TemplateCard 二选一：Button 仅将按钮替换为不可点击的文案，TemplateCard 替换整张卡片
+type TemplateCardUpdate struct {
+	UserIDs  []string `json:"userids,omitempty"`
+	PartyIDs []int    `json:"partyids,omitempty"`
+	TagIDs   []int    `json:"tagids,omitempty"`
+	AtAll    int      `json:"atall,omitempty"`
+	// 为 0 时使用 NewWithAgentIDOption 设置的应用 ID
+	AgentID int `json:"agentid"`
+	// 发送消息或回调事件返回的 response_code，72 小时内有效且只能使用一次
+	ResponseCode string         `json:"response_code"`
+	Button       *ReplaceButton `json:"button,omitempty"`
+	TemplateCard *TemplateCard  `json:"template_card,omitempty"`
+}
+
+type ReplaceButton struct {
+	ReplaceName string `json:"replace_name"`
+}
+
+// NewReplaceButtonUpdate 将卡片按钮替换为不可点击的文案，如“已同意”
+func NewReplaceButtonUpdate(responseCode, replaceName string) *TemplateCardUpdate {
+	return &TemplateCardUpdate{ResponseCode: responseCode, Button: &ReplaceButton{ReplaceName: replaceName}}
+}
+
+// NewReplaceCardUpdate 使用 card 替换整张卡片
+func NewReplaceCardUpdate(responseCode string, card *TemplateCard) *TemplateCardUpdate {
+	return &TemplateCardUpdate{ResponseCode: responseCode, TemplateCard: card}
+}
+
+// UpdateTemplateCard 应用消息：更新模版卡片消息
+// 参考链接：https://developer.work.weixin.qq.com/document/path/94888
+func (m *messageService) UpdateTemplateCard(update *TemplateCardUpdate) (result *MessageResp, err error) {
+	if update.ResponseCode == "" {
+		return nil, errors.New("response code is required")
+	}
+	if (update.Button == nil) == (update.TemplateCard == nil) {
+		return nil, errors.New("exactly one of button and template_card is required")
+	}
+	if update.TemplateCard != nil {
+		if err = update.TemplateCard.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	if update.AgentID == 0 {
+		if m.client.agentID == 0 {
+			return nil, errors.New("agent id is required")
+		}
+		c := *update
+		c.AgentID = m.client.agentID
+		update = &c
+	}
+
+	failCount := -1
+	// 默认尝试一次，即不进行失败重试
+	for failCount < m.client.maxRetryTimes {
+		failCount++
+		var req *http.Request
+		req, err = m.client.newRequest(http.MethodPost, pathMessageUpdateCard, update)
+		if err != nil {
+			continue // 如果循环结束，则会返回该 err
+		}
+		result = new(MessageResp)
+		err = (*service)(m).doRequest(req, result)
+		if err != nil {
+			continue // 如果循环结束，则会返回该 err
+		}
+		// 成功，return
+		return result, nil
+	}
+	// 失败，返回最后一次请求的 err
+	return nil, err
+}
@@ -0,0 +1,112 @@
+package wecom
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestMessageJSONRoundTrip(t *testing.T) {
+	msg := NewMessage(NewTextContent("hi")).ToUsers("a", "b").ToParties("1").ToTags("2", "3").WithAgentID(7).WithSafe(1).WithDuplicateCheck(60)
+	data, err := json.Marshal(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fields map[string]interface{}
+	if err = json.Unmarshal(data, &fields); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		"touser": "a|b", "toparty": "1", "totag": "2|3", "agentid": float64(7), "msgtype": "text",
+		"text": map[string]interface{}{"content": "hi"}, "safe": float64(1),
+		"enable_duplicate_check": float64(1), "duplicate_check_interval": float64(60),
+	}
+	if !reflect.DeepEqual(fields, want) {
+		t.Fatalf("got %s", data)
+	}
+
+	got := new(Message)
+	if err = json.Unmarshal(data, got); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, msg) {
+		t.Fatalf("round trip: got %+v, want %+v", got, msg)
+	}
+
+	// 未设置的接收人不输出，解析后为 nil
+	data, _ = json.Marshal(NewMessage(NewMarkdownContent("x")).ToAllUsers())
+	got = new(Message)
+	if err = json.Unmarshal(data, got); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got.ToUser, []string{ToAll}) || got.ToParty != nil || got.ToTag != nil {
+		t.Fatalf("got %s", data)
+	}
+}
+
+func TestMessageContentBuilders(t *testing.T) {
+	tests := []struct {
+		content *MessageContent
+		want    string
+	}{
+		{NewTextContent("t"), `{"msgtype":"text","text":{"content":"t"}}`},
+		{NewImageContent("m"), `{"msgtype":"image","image":{"media_id":"m"}}`},
+		{NewVoiceContent("m"), `{"msgtype":"voice","voice":{"media_id":"m"}}`},
+		{NewFileContent("m"), `{"msgtype":"file","file":{"media_id":"m"}}`},
+		{NewMarkdownContent("**b**"), `{"msgtype":"markdown","markdown":{"content":"**b**"}}`},
+	}
+	for _, tt := range tests {
+		data, err := json.Marshal(tt.content)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != tt.want {
+			t.Errorf("got %s, want %s", data, tt.want)
+		}
+	}
+	if c := NewVideoContent("m", "title", "desc"); c.MsgType != MsgTypeVideo || c.Video.MediaID != "m" || c.Video.Title != "title" {
+		t.Errorf("got %+v", c.Video)
+	}
+	if c := NewTextCardContent("title", "desc", "https://a", ""); c.MsgType != MsgTypeTextCard || c.TextCard.URL != "https://a" {
+		t.Errorf("got %+v", c.TextCard)
+	}
+	if c := NewNewsContent(NewsArticle{Title: "a"}, NewsArticle{Title: "b"}); c.MsgType != MsgTypeNews || len(c.News.Articles) != 2 {
+		t.Errorf("got %+v", c.News)
+	}
+}
+
+func TestSend(t *testing.T) {
+	var body map[string]interface{}
+	c, _ := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != pathMessageSend {
+			t.Errorf("unexpected request: %s", r.URL.Path)
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		fmt.Fprint(w, `{"errcode":0,"invaliduser":"x|y","msgid":"m1"}`)
+	}, NewWithAgentIDOption(1000002))
+
+	msg := NewMessage(NewTextContent("hi")).ToUsers("a", "x", "y")
+	result, err := c.Message.Send(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body["agentid"] != float64(1000002) || body["touser"] != "a|x|y" {
+		t.Fatalf("got request %v", body)
+	}
+	if msg.AgentID != 0 {
+		t.Fatal("Send modified the caller's message")
+	}
+	if result.MsgID != "m1" || !reflect.DeepEqual(result.InvalidUsers(), []string{"x", "y"}) || result.InvalidParties() != nil {
+		t.Fatalf("got %+v", result)
+	}
+
+	if _, err = c.Message.Send(NewMessage(NewTextContent("hi"))); err == nil {
+		t.Fatal("want error for empty recipients")
+	}
+	c.agentID = 0
+	if _, err = c.Message.Send(NewMessage(NewTextContent("hi")).ToUsers("a")); err == nil {
+		t.Fatal("want error without agent id")
+	}
+}
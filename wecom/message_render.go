@@ -0,0 +1,384 @@
+// message_render.go 使用 text/template 渲染消息内容，并在发送前校验长度和 markdown 语法
+package wecom
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+	"unicode/utf8"
+)
+
+// 消息内容长度限制，单位为字节
+const (
+	MaxTextBytes                = 2048
+	MaxMarkdownBytes            = 4096
+	MaxTextCardTitleBytes       = 128
+	MaxTextCardDescriptionBytes = 512
+)
+
+// ContentTemplate 消息内容模板，支持 text、markdown、textcard
+type ContentTemplate struct {
+	msgType MsgType
+	// text、markdown 的内容，textcard 的描述
+	body  *template.Template
+	title *template.Template
+	url   *template.Template
+	// textcard 按钮文字
+	btnTxt string
+}
+
+func parseTemplate(name, text string) (*template.Template, error) {
+	return template.New(name).Option("missingkey=error").Parse(text)
+}
+
+// NewTextTemplate 文本消息模板
+func NewTextTemplate(body string) (*ContentTemplate, error) {
+	t, err := parseTemplate("text", body)
+	if err != nil {
+		return nil, err
+	}
+	return &ContentTemplate{msgType: MsgTypeText, body: t}, nil
+}
+
+// NewMarkdownTemplate markdown 消息模板
+func NewMarkdownTemplate(body string) (*ContentTemplate, error) {
+	t, err := parseTemplate("markdown", body)
+	if err != nil {
+		return nil, err
+	}
+	return &ContentTemplate{msgType: MsgTypeMarkdown, body: t}, nil
+}
+
+// NewTextCardTemplate 文本卡片消息模板，title、description、url 均为模板
+func NewTextCardTemplate(title, description, url, btnTxt string) (*ContentTemplate, error) {
+	ct := &ContentTemplate{msgType: MsgTypeTextCard, btnTxt: btnTxt}
+	var err error
+	if ct.title, err = parseTemplate("title", title); err != nil {
+		return nil, err
+	}
+	if ct.body, err = parseTemplate("description", description); err != nil {
+		return nil, err
+	}
+	if ct.url, err = parseTemplate("url", url); err != nil {
+		return nil, err
+	}
+	return ct, nil
+}
+
+func execute(t *template.Template, data interface{}) (string, error) {
+	buf := &bytes.Buffer{}
+	if err := t.Execute(buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// Render 渲染并校验消息内容，超出长度限制或 markdown 语法不支持时返回错误
+func (t *ContentTemplate) Render(data interface{}) (*MessageContent, error) {
+	content, err := t.render(data)
+	if err != nil {
+		return nil, err
+	}
+	if err = ValidateContent(content); err != nil {
+		return nil, err
+	}
+	return content, nil
+}
+
+// RenderTruncate 渲染消息内容，超出长度限制的部分在 UTF-8 边界处截断并以 "..." 结尾
+// markdown 消息不会在标签内部或 <font>...</font> 之间截断
+func (t *ContentTemplate) RenderTruncate(data interface{}) (*MessageContent, error) {
+	content, err := t.render(data)
+	if err != nil {
+		return nil, err
+	}
+	switch t.msgType {
+	case MsgTypeText:
+		content.Text.Content = Truncate(content.Text.Content, MaxTextBytes)
+	case MsgTypeMarkdown:
+		content.Markdown.Content = TruncateMarkdown(content.Markdown.Content, MaxMarkdownBytes)
+	case MsgTypeTextCard:
+		content.TextCard.Title = Truncate(content.TextCard.Title, MaxTextCardTitleBytes)
+		content.TextCard.Description = Truncate(content.TextCard.Description, MaxTextCardDescriptionBytes)
+	}
+	if err = ValidateContent(content); err != nil {
+		return nil, err
+	}
+	return content, nil
+}
+
+// RenderSplit 渲染消息内容，超出长度限制时拆分为多条消息，仅支持 text、markdown
+func (t *ContentTemplate) RenderSplit(data interface{}) ([]*MessageContent, error) {
+	if t.msgType != MsgTypeText && t.msgType != MsgTypeMarkdown {
+		return nil, fmt.Errorf("msgtype %s cannot be split", t.msgType)
+	}
+	content, err := t.render(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var contents []*MessageContent
+	if t.msgType == MsgTypeText {
+		for _, s := range SplitContent(content.Text.Content, MaxTextBytes) {
+			contents = append(contents, NewTextContent(s))
+		}
+		return contents, nil
+	}
+	parts, err := splitMarkdown(content.Markdown.Content, MaxMarkdownBytes)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range parts {
+		c := NewMarkdownContent(s)
+		if err = ValidateContent(c); err != nil {
+			return nil, err
+		}
+		contents = append(contents, c)
+	}
+	return contents, nil
+}
+
+func (t *ContentTemplate) render(data interface{}) (*MessageContent, error) {
+	body, err := execute(t.body, data)
+	if err != nil {
+		return nil, err
+	}
+	switch t.msgType {
+	case MsgTypeText:
+		return NewTextContent(body), nil
+	case MsgTypeMarkdown:
+		return NewMarkdownContent(body), nil
+	}
+	title, err := execute(t.title, data)
+	if err != nil {
+		return nil, err
+	}
+	url, err := execute(t.url, data)
+	if err != nil {
+		return nil, err
+	}
+	return NewTextCardContent(title, body, url, t.btnTxt), nil
+}
+
+// ValidateContent 校验 text、markdown、textcard 消息的长度，以及 markdown 语法，其他类型不做校验
+func ValidateContent(c *MessageContent) error {
+	if c == nil {
+		return errors.New("message content is required")
+	}
+	switch c.MsgType {
+	case MsgTypeText:
+		if c.Text == nil {
+			return errors.New("text is required")
+		}
+		return checkBytes("text.content", c.Text.Content, MaxTextBytes)
+	case MsgTypeMarkdown:
+		if c.Markdown == nil {
+			return errors.New("markdown is required")
+		}
+		if err := checkBytes("markdown.content", c.Markdown.Content, MaxMarkdownBytes); err != nil {
+			return err
+		}
+		return ValidateMarkdown(c.Markdown.Content)
+	case MsgTypeTextCard:
+		if c.TextCard == nil {
+			return errors.New("textcard is required")
+		}
+		if err := checkBytes("textcard.title", c.TextCard.Title, MaxTextCardTitleBytes); err != nil {
+			return err
+		}
+		return checkBytes("textcard.description", c.TextCard.Description, MaxTextCardDescriptionBytes)
+	}
+	return nil
+}
+
+func checkBytes(field, s string, max int) error {
+	if len(s) > max {
+		return fmt.Errorf("%s is %d bytes, exceeds %d bytes", field, len(s), max)
+	}
+	if !utf8.ValidString(s) {
+		return fmt.Errorf("%s is not valid UTF-8", field)
+	}
+	return nil
+}
+
+var (
+	// 标签名之后只允许出现属性，避免将 a<b && c>d 之类的普通文本识别为标签
+	markdownTag      = regexp.MustCompile(`</?([a-zA-Z]+)(?:\s+[a-zA-Z-]+(?:\s*=\s*(?:"[^"]*"|'[^']*'|[^\s"'>]+))?)*\s*/?>`)
+	markdownFontOpen = regexp.MustCompile(`^<font\s+color\s*=\s*"(info|comment|warning)"\s*>$`)
+	markdownImage    = regexp.MustCompile(`!\[[^\]]*\]\([^)]*\)`)
+	markdownTable    = regexp.MustCompile(`(?m)^\s*\|.*\|\s*$`)
+)
+
+// ValidateMarkdown 校验应用消息 markdown 语法
+// 支持标题、加粗、链接、行内代码、引用，以及 <font color="info|comment|warning"> 颜色标签
+// 图片、表格、代码块及其他 HTML 标签均不支持
+// 参考链接：https://developer.work.weixin.qq.com/document/path/90236#markdown消息
+func ValidateMarkdown(s string) error {
+	if markdownImage.MatchString(s) {
+		return fmt.Errorf("markdown: image is not supported")
+	}
+	if markdownTable.MatchString(s) {
+		return fmt.Errorf("markdown: table is not supported")
+	}
+	if strings.Contains(s, "```") {
+		return fmt.Errorf("markdown: code block is not supported, use inline code instead")
+	}
+
+	depth := 0
+	for _, m := range markdownTag.FindAllStringSubmatch(s, -1) {
+		tag, name := m[0], strings.ToLower(m[1])
+		if name != "font" {
+			return fmt.Errorf("markdown: tag %s is not supported", tag)
+		}
+		if strings.HasPrefix(tag, "</") {
+			depth--
+			if depth < 0 {
+				return fmt.Errorf("markdown: unexpected %s", tag)
+			}
+			continue
+		}
+		if !markdownFontOpen.MatchString(tag) {
+			return fmt.Errorf("markdown: %s is not supported, color must be info, comment or warning", tag)
+		}
+		depth++
+	}
+	if depth != 0 {
+		return fmt.Errorf("markdown: unclosed font tag")
+	}
+	return nil
+}
+
+const truncateSuffix = "..."
+
+// Truncate 将 s 截断到不超过 max 字节，截断位置在 UTF-8 字符边界上，截断后以 "..." 结尾
+func Truncate(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	if max <= len(truncateSuffix) {
+		return truncateUTF8(s, max)
+	}
+	return truncateUTF8(s, max-len(truncateSuffix)) + truncateSuffix
+}
+
+// TruncateMarkdown 与 Truncate 相同，但不会在标签内部或 <font>...</font> 之间截断
+// 截断位置之后的 font 标签会被整体去掉，保证结果仍能通过 ValidateMarkdown
+func TruncateMarkdown(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	suffix := truncateSuffix
+	if max <= len(suffix) {
+		suffix = ""
+	}
+	cuttable := markdownCuttable(s)
+	i := len(truncateUTF8(s, max-len(suffix)))
+	for i > 0 && !(utf8.RuneStart(s[i]) && cuttable(i)) {
+		i--
+	}
+	return s[:i] + suffix
+}
+
+// truncateUTF8 返回不超过 max 字节的最长前缀，不会截断多字节字符
+func truncateUTF8(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	for max > 0 && !utf8.RuneStart(s[max]) {
+		max--
+	}
+	return s[:max]
+}
+
+// SplitContent 将 s 拆分为多段，每段不超过 max 字节
+// 优先在换行处拆分，单行超长时在 UTF-8 字符边界处拆分，拆分处的换行会被去掉，不会产生空段
+func SplitContent(s string, max int) []string {
+	parts, _ := splitAt(s, max, func(int) bool { return true })
+	return parts
+}
+
+// splitMarkdown 与 SplitContent 相同，但不会在标签内部或 <font>...</font> 之间拆分
+func splitMarkdown(s string, max int) ([]string, error) {
+	return splitAt(s, max, markdownCuttable(s))
+}
+
+// splitAt 拆分 s，仅在 cuttable 返回 true 的位置拆分，找不到可拆分的位置时返回错误
+func splitAt(s string, max int, cuttable func(offset int) bool) ([]string, error) {
+	var parts []string
+	// offset 为 s 在原始字符串中的位置
+	offset := 0
+	for len(s) > max {
+		cut := splitPoint(s, max, func(i int) bool { return cuttable(offset + i) })
+		if cut == 0 {
+			// 剩余部分原样返回，SplitContent 在 max 小于单个字符长度时依赖该行为
+			return append(parts, s), fmt.Errorf("content cannot be split into parts of %d bytes", max)
+		}
+		if head := strings.TrimRight(s[:cut], "\n"); head != "" {
+			parts = append(parts, head)
+		}
+		rest := strings.TrimLeft(s[cut:], "\n")
+		offset += len(s) - len(rest)
+		s = rest
+	}
+	if s != "" {
+		parts = append(parts, s)
+	}
+	return parts, nil
+}
+
+// splitPoint 返回不超过 max 的拆分位置，优先选择换行之后的位置，找不到时返回 0
+func splitPoint(s string, max int, cuttable func(i int) bool) int {
+	head := truncateUTF8(s, max)
+	for i := strings.LastIndexByte(head, '\n'); i >= 0; i = strings.LastIndexByte(head[:i], '\n') {
+		if cuttable(i + 1) {
+			return i + 1
+		}
+	}
+	for i := len(head); i > 0; i-- {
+		if utf8.RuneStart(s[i]) && cuttable(i) {
+			return i
+		}
+	}
+	return 0
+}
+
+// markdownCuttable 返回判断某个位置是否可以拆分的函数，标签内部及 font 标签之间的位置不可拆分
+func markdownCuttable(s string) func(offset int) bool {
+	var spans [][2]int
+	depth, start := 0, 0
+	for _, loc := range markdownTag.FindAllStringSubmatchIndex(s, -1) {
+		tag, name := s[loc[0]:loc[1]], strings.ToLower(s[loc[2]:loc[3]])
+		switch {
+		case name != "font":
+			spans = append(spans, [2]int{loc[0], loc[1]})
+		case strings.HasPrefix(tag, "</"):
+			if depth == 0 {
+				spans = append(spans, [2]int{loc[0], loc[1]})
+				continue
+			}
+			depth--
+			if depth == 0 {
+				spans = append(spans, [2]int{start, loc[1]})
+			}
+		default:
+			if depth == 0 {
+				start = loc[0]
+			}
+			depth++
+		}
+	}
+	if depth > 0 {
+		spans = append(spans, [2]int{start, len(s)})
+	}
+	return func(offset int) bool {
+		for _, span := range spans {
+			if span[0] < offset && offset < span[1] {
+				return false
+			}
+		}
+		return true
+	}
+}
@@ -0,0 +1,103 @@
+package wecom
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestSplitContentDropsEmptyParts(t *testing.T) {
+	parts := SplitContent(strings.Repeat("\n", 5000)+"x", MaxTextBytes)
+	if len(parts) != 1 || parts[0] != "x" {
+		t.Fatalf("got %d parts, want [x]", len(parts))
+	}
+
+	s := strings.Repeat("a", 10) + "\n" + strings.Repeat("b", 10)
+	parts = SplitContent(s, 15)
+	if len(parts) != 2 || parts[0] != strings.Repeat("a", 10) || parts[1] != strings.Repeat("b", 10) {
+		t.Fatalf("got %q", parts)
+	}
+}
+
+func TestSplitContentUTF8(t *testing.T) {
+	s := strings.Repeat("中", 10)
+	for _, part := range SplitContent(s, 7) {
+		if len(part) > 7 || !utf8.ValidString(part) {
+			t.Fatalf("invalid part %q", part)
+		}
+	}
+	if got := strings.Join(SplitContent(s, 7), ""); got != s {
+		t.Fatalf("got %q, want %q", got, s)
+	}
+}
+
+func TestRenderSplitKeepsFontTags(t *testing.T) {
+	tpl, err := NewMarkdownTemplate(`{{range .}}<font color="info">{{.}}</font>{{end}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var items []string
+	for i := 0; i < 300; i++ {
+		items = append(items, strings.Repeat("x", 20))
+	}
+	contents, err := tpl.RenderSplit(items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(contents) < 2 {
+		t.Fatalf("got %d parts, want at least 2", len(contents))
+	}
+	for _, c := range contents {
+		if err = ValidateContent(c); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestValidateContentNilBody(t *testing.T) {
+	for _, c := range []*MessageContent{
+		nil,
+		{MsgType: MsgTypeText},
+		{MsgType: MsgTypeMarkdown},
+		{MsgType: MsgTypeTextCard},
+	} {
+		if err := ValidateContent(c); err == nil {
+			t.Fatalf("%+v: got nil error", c)
+		}
+	}
+}
+
+func TestValidateMarkdown(t *testing.T) {
+	cases := map[string]bool{
+		`**bold** <font color="warning">x</font>`: true,
+		`<font color="red">x</font>`:              false,
+		`<font color="info">x`:                    false,
+		`x</font>`:                                false,
+		"```go\nx\n```":                           false,
+		`![img](http://a/b.png)`:                  false,
+		`<b>x</b>`:                                false,
+		`a<b && c>d`:                              true,
+	}
+	for s, ok := range cases {
+		if err := ValidateMarkdown(s); (err == nil) != ok {
+			t.Errorf("%q: got %v", s, err)
+		}
+	}
+}
+
+func TestRenderTruncateKeepsFontTags(t *testing.T) {
+	tpl, err := NewMarkdownTemplate(`{{.}}<font color="info">` + strings.Repeat("y", 100) + `</font>`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, n := range []int{4000, 4080, MaxMarkdownBytes - 10} {
+		content, err := tpl.RenderTruncate(strings.Repeat("x", n))
+		if err != nil {
+			t.Fatalf("%d: %v", n, err)
+		}
+		got := content.Markdown.Content
+		if len(got) > MaxMarkdownBytes || strings.Contains(got, "<font") || !strings.HasSuffix(got, "...") {
+			t.Fatalf("%d: got %d bytes ending with %q", n, len(got), got[len(got)-20:])
+		}
+	}
+}
@@ -0,0 +1,290 @@
+// message_template_card.go 模板卡片消息
+// 参考链接：https://developer.work.weixin.qq.com/document/path/90236#模板卡片消息
+package wecom
+
+import (
+	"errors"
+	"fmt"
+)
+
+// 模板卡片类型
+const (
+	CardTypeTextNotice          = "text_notice"          // 文本通知型
+	CardTypeNewsNotice          = "news_notice"          // 图文展示型
+	CardTypeButtonInteraction   = "button_interaction"   // 按钮交互型
+	CardTypeVoteInteraction     = "vote_interaction"     // 投票选择型
+	CardTypeMultipleInteraction = "multiple_interaction" // 多项选择型
+)
+
+// 卡片跳转类型
+const (
+	CardActionURL         = 1 // 跳转 url
+	CardActionMiniprogram = 2 // 跳转小程序
+)
+
+// TemplateCard 模板卡片，不同的 CardType 支持的字段不同，建议使用 NewXXXCard 创建
+type TemplateCard struct {
+	CardType              string                  `json:"card_type"`
+	Source                *CardSource             `json:"source,omitempty"`
+	ActionMenu            *CardActionMenu         `json:"action_menu,omitempty"`
+	TaskID                string                  `json:"task_id,omitempty"`
+	MainTitle             *CardTitle              `json:"main_title,omitempty"`
+	QuoteArea             *CardQuoteArea          `json:"quote_area,omitempty"`
+	EmphasisContent       *CardTitle              `json:"emphasis_content,omitempty"`
+	SubTitleText          string                  `json:"sub_title_text,omitempty"`
+	HorizontalContentList []CardHorizontalContent `json:"horizontal_content_list,omitempty"`
+	JumpList              []CardJump              `json:"jump_list,omitempty"`
+	CardAction            *CardAction             `json:"card_action,omitempty"`
+	CardImage             *CardImage              `json:"card_image,omitempty"`
+	ImageTextArea         *CardImageTextArea      `json:"image_text_area,omitempty"`
+	VerticalContentList   []CardTitle             `json:"vertical_content_list,omitempty"`
+	ButtonSelection       *CardSelect             `json:"button_selection,omitempty"`
+	ButtonList            []CardButton            `json:"button_list,omitempty"`
+	CheckBox              *CardCheckBox           `json:"checkbox,omitempty"`
+	SelectList            []CardSelect            `json:"select_list,omitempty"`
+	SubmitButton          *CardSubmitButton       `json:"submit_button,omitempty"`
+	// 仅更新卡片时有效，按钮交互型卡片点击后替换的按钮文案
+	ReplaceText string `json:"replace_text,omitempty"`
+}
+
+// 卡片来源
+type CardSource struct {
+	IconURL string `json:"icon_url,omitempty"`
+	Desc    string `json:"desc,omitempty"`
+	// 来源文字的颜色，0 灰色，1 黑色，2 红色，3 绿色
+	DescColor int `json:"desc_color,omitempty"`
+}
+
+// 卡片右上角更多操作按钮
+type CardActionMenu struct {
+	Desc       string               `json:"desc,omitempty"`
+	ActionList []CardActionMenuItem `json:"action_list"`
+}
+
+type CardActionMenuItem struct {
+	Text string `json:"text"`
+	Key  string `json:"key"`
+}
+
+// 标题与描述，用于主标题、关键数据、二级垂直内容
+type CardTitle struct {
+	Title string `json:"title,omitempty"`
+	Desc  string `json:"desc,omitempty"`
+}
+
+// 引用文献
+type CardQuoteArea struct {
+	Type      int    `json:"type,omitempty"`
+	URL       string `json:"url,omitempty"`
+	Appid     string `json:"appid,omitempty"`
+	Pagepath  string `json:"pagepath,omitempty"`
+	Title     string `json:"title,omitempty"`
+	QuoteText string `json:"quote_text,omitempty"`
+}
+
+// 二级标题 + 文本
+// type 为 0 或不填表示普通文本，1 表示跳转 url，2 表示下载附件，3 表示成员详情
+type CardHorizontalContent struct {
+	Type    int    `json:"type,omitempty"`
+	Keyname string `json:"keyname"`
+	Value   string `json:"value,omitempty"`
+	URL     string `json:"url,omitempty"`
+	MediaID string `json:"media_id,omitempty"`
+	Userid  string `json:"userid,omitempty"`
+}
+
+// 跳转指引
+type CardJump struct {
+	Type     int    `json:"type,omitempty"`
+	Title    string `json:"title"`
+	URL      string `json:"url,omitempty"`
+	Appid    string `json:"appid,omitempty"`
+	Pagepath string `json:"pagepath,omitempty"`
+}
+
+// 整体卡片的点击跳转事件
+type CardAction struct {
+	Type     int    `json:"type"`
+	URL      string `json:"url,omitempty"`
+	Appid    string `json:"appid,omitempty"`
+	Pagepath string `json:"pagepath,omitempty"`
+}
+
+// 图片样式
+type CardImage struct {
+	URL         string  `json:"url"`
+	AspectRatio float64 `json:"aspect_ratio,omitempty"`
+}
+
+// 左图右文样式
+type CardImageTextArea struct {
+	Type     int    `json:"type,omitempty"`
+	URL      string `json:"url,omitempty"`
+	Appid    string `json:"appid,omitempty"`
+	Pagepath string `json:"pagepath,omitempty"`
+	Title    string `json:"title,omitempty"`
+	Desc     string `json:"desc,omitempty"`
+	ImageURL string `json:"image_url"`
+}
+
+// 下拉式的选择器
+type CardSelect struct {
+	QuestionKey string       `json:"question_key"`
+	Title       string       `json:"title,omitempty"`
+	Disable     bool         `json:"disable,omitempty"`
+	SelectedID  string       `json:"selected_id,omitempty"`
+	OptionList  []CardOption `json:"option_list"`
+}
+
+type CardOption struct {
+	ID        string `json:"id"`
+	Text      string `json:"text"`
+	IsChecked bool   `json:"is_checked,omitempty"`
+}
+
+// 按钮，type 为 0 表示回调点击事件，1 表示跳转 url
+// style 为按钮样式，取值 1 ~ 4
+type CardButton struct {
+	Type  int    `json:"type,omitempty"`
+	Text  string `json:"text"`
+	Style int    `json:"style,omitempty"`
+	Key   string `json:"key,omitempty"`
+	URL   string `json:"url,omitempty"`
+}
+
+// 选择题样式，mode 为 0 表示单选，1 表示多选
+type CardCheckBox struct {
+	QuestionKey string       `json:"question_key"`
+	OptionList  []CardOption `json:"option_list"`
+	Disable     bool         `json:"disable,omitempty"`
+	Mode        int          `json:"mode,omitempty"`
+}
+
+// 提交按钮
+type CardSubmitButton struct {
+	Text string `json:"text"`
+	Key  string `json:"key"`
+}
+
+// NewURLCardAction 点击卡片跳转 url
+func NewURLCardAction(url string) *CardAction {
+	return &CardAction{Type: CardActionURL, URL: url}
+}
+
+// NewMiniprogramCardAction 点击卡片跳转小程序
+func NewMiniprogramCardAction(appid, pagepath string) *CardAction {
+	return &CardAction{Type: CardActionMiniprogram, Appid: appid, Pagepath: pagepath}
+}
+
+// NewTextNoticeCard 文本通知型卡片
+func NewTextNoticeCard(title, desc string, action *CardAction) *TemplateCard {
+	return &TemplateCard{
+		CardType:   CardTypeTextNotice,
+		MainTitle:  &CardTitle{Title: title, Desc: desc},
+		CardAction: action,
+	}
+}
+
+// NewNewsNoticeCard 图文展示型卡片，image 与 imageTextArea 至少填写一个
+func NewNewsNoticeCard(title, desc string, image *CardImage, action *CardAction) *TemplateCard {
+	return &TemplateCard{
+		CardType:   CardTypeNewsNotice,
+		MainTitle:  &CardTitle{Title: title, Desc: desc},
+		CardImage:  image,
+		CardAction: action,
+	}
+}
+
+// NewButtonInteractionCard 按钮交互型卡片，taskID 用于更新卡片及接收回调
+func NewButtonInteractionCard(taskID, title, desc string, buttons ...CardButton) *TemplateCard {
+	return &TemplateCard{
+		CardType:   CardTypeButtonInteraction,
+		TaskID:     taskID,
+		MainTitle:  &CardTitle{Title: title, Desc: desc},
+		ButtonList: buttons,
+	}
+}
+
+// NewVoteInteractionCard 投票选择型卡片
+func NewVoteInteractionCard(taskID, title, desc string, checkbox *CardCheckBox, submit *CardSubmitButton) *TemplateCard {
+	return &TemplateCard{
+		CardType:     CardTypeVoteInteraction,
+		TaskID:       taskID,
+		MainTitle:    &CardTitle{Title: title, Desc: desc},
+		CheckBox:     checkbox,
+		SubmitButton: submit,
+	}
+}
+
+// NewMultipleInteractionCard 多项选择型卡片
+func NewMultipleInteractionCard(taskID, title, desc string, selects []CardSelect, submit *CardSubmitButton) *TemplateCard {
+	return &TemplateCard{
+		CardType:     CardTypeMultipleInteraction,
+		TaskID:       taskID,
+		MainTitle:    &CardTitle{Title: title, Desc: desc},
+		SelectList:   selects,
+		SubmitButton: submit,
+	}
+}
+
+// Validate 检查各类型卡片的必填字段及数量限制
+func (c *TemplateCard) Validate() error {
+	switch c.CardType {
+	case CardTypeTextNotice:
+		if (c.MainTitle == nil || c.MainTitle.Title == "") && c.SubTitleText == "" {
+			return errors.New("text_notice: main_title.title and sub_title_text cannot be empty at the same time")
+		}
+		if c.CardAction == nil {
+			return errors.New("text_notice: card_action is required")
+		}
+	case CardTypeNewsNotice:
+		if c.MainTitle == nil || c.MainTitle.Title == "" {
+			return errors.New("news_notice: main_title.title is required")
+		}
+		if c.CardImage == nil && c.ImageTextArea == nil {
+			return errors.New("news_notice: card_image and image_text_area cannot be empty at the same time")
+		}
+		if c.CardAction == nil {
+			return errors.New("news_notice: card_action is required")
+		}
+		if len(c.VerticalContentList) > 4 {
+			return errors.New("news_notice: vertical_content_list cannot exceed 4 items")
+		}
+	case CardTypeButtonInteraction:
+		if c.TaskID == "" {
+			return errors.New("button_interaction: task_id is required")
+		}
+		if len(c.ButtonList) == 0 || len(c.ButtonList) > 6 {
+			return fmt.Errorf("button_interaction: button_list must have 1 ~ 6 items, got %d", len(c.ButtonList))
+		}
+	case CardTypeVoteInteraction:
+		if c.TaskID == "" {
+			return errors.New("vote_interaction: task_id is required")
+		}
+		if c.CheckBox == nil || len(c.CheckBox.OptionList) == 0 || len(c.CheckBox.OptionList) > 20 {
+			return errors.New("vote_interaction: checkbox.option_list must have 1 ~ 20 items")
+		}
+		if c.SubmitButton == nil {
+			return errors.New("vote_interaction: submit_button is required")
+		}
+	case CardTypeMultipleInteraction:
+		if c.TaskID == "" {
+			return errors.New("multiple_interaction: task_id is required")
+		}
+		if len(c.SelectList) == 0 || len(c.SelectList) > 3 {
+			return fmt.Errorf("multiple_interaction: select_list must have 1 ~ 3 items, got %d", len(c.SelectList))
+		}
+		if c.SubmitButton == nil {
+			return errors.New("multiple_interaction: submit_button is required")
+		}
+	default:
+		return fmt.Errorf("invalid card type: %s", c.CardType)
+	}
+	if len(c.HorizontalContentList) > 6 {
+		return errors.New("horizontal_content_list cannot exceed 6 items")
+	}
+	if len(c.JumpList) > 3 {
+		return errors.New("jump_list cannot exceed 3 items")
+	}
+	return nil
+}
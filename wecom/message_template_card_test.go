@@ -0,0 +1,93 @@
+package wecom
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestTemplateCardValidate(t *testing.T) {
+	action := NewURLCardAction("https://a")
+	submit := &CardSubmitButton{Text: "提交", Key: "k"}
+	options := []CardOption{{ID: "1", Text: "a"}}
+	tests := []struct {
+		name string
+		card *TemplateCard
+		ok   bool
+	}{
+		{"text_notice", NewTextNoticeCard("t", "", action), true},
+		{"text_notice sub title only", &TemplateCard{CardType: CardTypeTextNotice, SubTitleText: "s", CardAction: action}, true},
+		{"text_notice without title", NewTextNoticeCard("", "", action), false},
+		{"text_notice without action", NewTextNoticeCard("t", "", nil), false},
+		{"news_notice", NewNewsNoticeCard("t", "", &CardImage{URL: "https://a/b.png"}, action), true},
+		{"news_notice without image", NewNewsNoticeCard("t", "", nil, action), false},
+		{"news_notice without title", NewNewsNoticeCard("", "", &CardImage{URL: "u"}, action), false},
+		{"button_interaction", NewButtonInteractionCard("task", "t", "", CardButton{Text: "ok", Key: "k"}), true},
+		{"button_interaction without task", NewButtonInteractionCard("", "t", "", CardButton{Text: "ok", Key: "k"}), false},
+		{"button_interaction without buttons", NewButtonInteractionCard("task", "t", ""), false},
+		{"button_interaction too many buttons", NewButtonInteractionCard("task", "t", "", make([]CardButton, 7)...), false},
+		{"vote_interaction", NewVoteInteractionCard("task", "t", "", &CardCheckBox{QuestionKey: "q", OptionList: options}, submit), true},
+		{"vote_interaction without options", NewVoteInteractionCard("task", "t", "", &CardCheckBox{QuestionKey: "q"}, submit), false},
+		{"vote_interaction without submit", NewVoteInteractionCard("task", "t", "", &CardCheckBox{QuestionKey: "q", OptionList: options}, nil), false},
+		{"multiple_interaction", NewMultipleInteractionCard("task", "t", "", []CardSelect{{QuestionKey: "q", OptionList: options}}, submit), true},
+		{"multiple_interaction too many selects", NewMultipleInteractionCard("task", "t", "", make([]CardSelect, 4), submit), false},
+		{"multiple_interaction without task", NewMultipleInteractionCard("", "t", "", []CardSelect{{QuestionKey: "q", OptionList: options}}, submit), false},
+		{"invalid type", &TemplateCard{CardType: "unknown"}, false},
+		{"too many jumps", &TemplateCard{CardType: CardTypeTextNotice, SubTitleText: "s", CardAction: action, JumpList: make([]CardJump, 4)}, false},
+	}
+	for _, tt := range tests {
+		if err := tt.card.Validate(); (err == nil) != tt.ok {
+			t.Errorf("%s: got %v", tt.name, err)
+		}
+	}
+}
+
+func TestRecallAndUpdateTemplateCard(t *testing.T) {
+	var path string
+	var body map[string]interface{}
+	c, _ := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		body = nil
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		fmt.Fprint(w, `{"errcode":0}`)
+	}, NewWithAgentIDOption(1000002))
+
+	if _, err := c.Message.Recall("m1"); err != nil {
+		t.Fatal(err)
+	}
+	if path != pathMessageRecall || body["msgid"] != "m1" {
+		t.Fatalf("got %s %v", path, body)
+	}
+
+	update := NewReplaceButtonUpdate("code", "已处理")
+	update.UserIDs = []string{"a"}
+	if _, err := c.Message.UpdateTemplateCard(update); err != nil {
+		t.Fatal(err)
+	}
+	button, _ := body["button"].(map[string]interface{})
+	if path != pathMessageUpdateCard || body["agentid"] != float64(1000002) || body["response_code"] != "code" ||
+		button["replace_name"] != "已处理" || body["template_card"] != nil {
+		t.Fatalf("got %s %v", path, body)
+	}
+
+	card := NewTextNoticeCard("t", "", NewURLCardAction("https://a"))
+	if _, err := c.Message.UpdateTemplateCard(NewReplaceCardUpdate("code", card)); err != nil {
+		t.Fatal(err)
+	}
+	if tc, _ := body["template_card"].(map[string]interface{}); tc["card_type"] != CardTypeTextNotice {
+		t.Fatalf("got %v", body)
+	}
+
+	invalid := []*TemplateCardUpdate{
+		NewReplaceButtonUpdate("", "x"),
+		{ResponseCode: "code"},
+		{ResponseCode: "code", Button: &ReplaceButton{}, TemplateCard: card},
+		NewReplaceCardUpdate("code", &TemplateCard{CardType: CardTypeTextNotice}),
+	}
+	for _, u := range invalid {
+		if _, err := c.Message.UpdateTemplateCard(u); err == nil {
+			t.Errorf("want error for %+v", u)
+		}
+	}
+}
@@ -0,0 +1,200 @@
+package wecom
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+)
+
+// MultipartFile multipart/form-data 格式的请求体，用于上传文件类接口
+// 作为 newRequest 的 body 时，文件内容以流的方式发送，不会整体读入内存
+type MultipartFile struct {
+	// part 名称，为空时默认为 media
+	FieldName string
+	Filename  string
+	// 文件大小，用于 filelength 及 Content-Length，小于 0 表示未知
+	Size int64
+	// Open 返回文件内容，每次构建请求（包括失败重试）都会调用一次
+	Open func() (io.ReadCloser, error)
+}
+
+// NewMultipartFile 使用内存中的数据创建 MultipartFile
+func NewMultipartFile(filename string, data []byte) *MultipartFile {
+	return &MultipartFile{
+		Filename: filename,
+		Size:     int64(len(data)),
+		Open: func() (io.ReadCloser, error) {
+			return ioutil.NopCloser(bytes.NewReader(data)), nil
+		},
+	}
+}
+
+// NewMultipartFileFromPath 使用本地文件创建 MultipartFile，每次构建请求时重新打开文件
+func NewMultipartFileFromPath(path string) (*MultipartFile, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	return &MultipartFile{
+		Filename: filepath.Base(path),
+		Size:     info.Size(),
+		Open: func() (io.ReadCloser, error) {
+			return os.Open(path)
+		},
+	}, nil
+}
+
+// NewMultipartFileFromReader 使用 r 创建 MultipartFile，最多读取 max 字节
+// r 同时实现了 io.ReaderAt 和 io.Seeker（如 *os.File、*bytes.Reader）时，
+// 每次构建请求都会创建独立的 io.SectionReader，因此可以失败重试
+// 否则 r 只会被读取一次，文件内容以流的方式发送，Size 为 -1，超过 max 字节时请求失败；
+// 此时无法重放请求体，失败重试及 token 过期后的重试都会返回 ErrBodyNotReplayable
+func NewMultipartFileFromReader(filename string, r io.Reader, max int64) (*MultipartFile, error) {
+	readerAt, ok := r.(io.ReaderAt)
+	seeker, ok2 := r.(io.Seeker)
+	if !ok || !ok2 {
+		var opened int32
+		return &MultipartFile{
+			Filename: filename,
+			Size:     -1,
+			Open: func() (io.ReadCloser, error) {
+				if !atomic.CompareAndSwapInt32(&opened, 0, 1) {
+					return nil, ErrBodyNotReplayable
+				}
+				// 调用方的 reader 由调用方关闭
+				return ioutil.NopCloser(&maxBytesReader{r: r, max: max}), nil
+			},
+		}, nil
+	}
+
+	start, err := seeker.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, err
+	}
+	size, err := readerSize(r)
+	if err != nil {
+		return nil, err
+	}
+	if size > max {
+		return nil, fmt.Errorf("size exceeds %d bytes", max)
+	}
+	return &MultipartFile{
+		Filename: filename,
+		Size:     size,
+		Open: func() (io.ReadCloser, error) {
+			// 调用方的 reader 由调用方关闭
+			return ioutil.NopCloser(io.NewSectionReader(readerAt, start, size)), nil
+		},
+	}, nil
+}
+
+// ErrBodyNotReplayable 请求体来自只能读取一次的 io.Reader，无法重新发送
+var ErrBodyNotReplayable = errors.New("wecom: request body cannot be replayed, use a reader that implements io.ReaderAt and io.Seeker to enable retry")
+
+// maxBytesReader 读取超过 max 字节时返回错误
+type maxBytesReader struct {
+	r    io.Reader
+	max  int64
+	read int64
+}
+
+func (m *maxBytesReader) Read(p []byte) (int, error) {
+	n, err := m.r.Read(p)
+	m.read += int64(n)
+	if m.read > m.max {
+		return n, fmt.Errorf("size exceeds %d bytes", m.max)
+	}
+	return n, err
+}
+
+// readLimited 读取 r 的全部内容，超过 max 字节时返回错误
+func readLimited(r io.Reader, max int64) ([]byte, error) {
+	data, err := ioutil.ReadAll(io.LimitReader(r, max+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > max {
+		return nil, fmt.Errorf("size exceeds %d bytes", max)
+	}
+	return data, nil
+}
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+// body 使用指定的分隔符构建请求体，返回 Content-Type 及 Content-Length（未知时为 -1）
+// 请求体由 part 头部、文件内容、结束分隔符三部分拼接而成，只有头部和结束分隔符在内存中
+func (f *MultipartFile) body(boundary string) (io.ReadCloser, string, int64, error) {
+	buf := &bytes.Buffer{}
+	w := multipart.NewWriter(buf)
+	if boundary != "" {
+		if err := w.SetBoundary(boundary); err != nil {
+			return nil, "", 0, err
+		}
+	}
+	fieldName := f.FieldName
+	if fieldName == "" {
+		fieldName = "media"
+	}
+	h := make(textproto.MIMEHeader)
+	disposition := fmt.Sprintf(`form-data; name="%s"; filename="%s"`, quoteEscaper.Replace(fieldName), quoteEscaper.Replace(f.Filename))
+	if f.Size >= 0 {
+		disposition += fmt.Sprintf("; filelength=%d", f.Size)
+	}
+	h.Set("Content-Disposition", disposition)
+	h.Set("Content-Type", "application/octet-stream")
+	if _, err := w.CreatePart(h); err != nil {
+		return nil, "", 0, err
+	}
+	head := append([]byte(nil), buf.Bytes()...)
+	buf.Reset()
+	if err := w.Close(); err != nil {
+		return nil, "", 0, err
+	}
+	tail := append([]byte(nil), buf.Bytes()...)
+
+	file, err := f.Open()
+	if err != nil {
+		return nil, "", 0, err
+	}
+	length := int64(-1)
+	if f.Size >= 0 {
+		length = int64(len(head)) + f.Size + int64(len(tail))
+	}
+	body := &streamBody{
+		Reader: io.MultiReader(bytes.NewReader(head), file, bytes.NewReader(tail)),
+		Closer: file,
+	}
+	return body, w.FormDataContentType(), length, nil
+}
+
+// setMultipartBody 将 f 设置为 req 的请求体，并设置 GetBody 以便重试时重新构建
+// 重新构建的请求体使用相同的分隔符，以便与 Content-Type 保持一致
+func setMultipartBody(req *http.Request, f *MultipartFile) error {
+	body, contentType, length, err := f.body("")
+	if err != nil {
+		return err
+	}
+	_, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return err
+	}
+	boundary := params["boundary"]
+	req.Body = body
+	req.ContentLength = length
+	req.Header.Set("Content-Type", contentType)
+	req.GetBody = func() (io.ReadCloser, error) {
+		body, _, _, err := f.body(boundary)
+		return body, err
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package wecom
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"testing"
+)
+
+func TestMultipartBodyRebuiltOnRetry(t *testing.T) {
+	data := bytes.Repeat([]byte("0123456789"), 100)
+	var bodies [][]byte
+	c, _ := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		_, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		part, err := multipart.NewReader(r.Body, params["boundary"]).NextPart()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if part.FormName() != "media" || part.FileName() != "a.txt" {
+			t.Errorf("got part %s, file %s", part.FormName(), part.FileName())
+		}
+		content, _ := ioutil.ReadAll(part)
+		bodies = append(bodies, content)
+		if r.ContentLength <= int64(len(data)) {
+			t.Errorf("got content length %d", r.ContentLength)
+		}
+		if len(bodies) == 1 {
+			fmt.Fprint(w, `{"errcode":42001,"errmsg":"access_token expired"}`)
+			return
+		}
+		fmt.Fprint(w, `{"errcode":0,"type":"file","media_id":"m"}`)
+	})
+
+	result, err := c.Media.Upload(MediaTypeFile, "a.txt", bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.MediaID != "m" || len(bodies) != 2 {
+		t.Fatalf("got media_id %q after %d requests", result.MediaID, len(bodies))
+	}
+	for k, body := range bodies {
+		if !bytes.Equal(body, data) {
+			t.Fatalf("request %d: got %d bytes, want %d", k, len(body), len(data))
+		}
+	}
+}
+
+func TestNewMultipartFileFromReaderLimit(t *testing.T) {
+	if _, err := NewMultipartFileFromReader("a", bytes.NewReader(make([]byte, 11)), 10); err == nil {
+		t.Fatal("want error for seekable reader over limit")
+	}
+	for _, s := range []string{"0123456789", "01234567890"} {
+		f, err := NewMultipartFileFromReader("a", bytes.NewBufferString(s), 10)
+		if err != nil || f.Size != -1 {
+			t.Fatalf("got %+v, %v", f, err)
+		}
+		body, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = ioutil.ReadAll(body); (err == nil) != (len(s) <= 10) {
+			t.Fatalf("%d bytes: got err %v", len(s), err)
+		}
+		if _, err = f.Open(); err != ErrBodyNotReplayable {
+			t.Fatalf("got %v, want ErrBodyNotReplayable", err)
+		}
+	}
+}
+
+func TestUploadNonSeekableReader(t *testing.T) {
+	data := bytes.Repeat([]byte("0123456789"), 100)
+	expired := false
+	c, _ := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		content, _ := ioutil.ReadAll(r.Body)
+		if r.ContentLength != -1 || !bytes.Contains(content, data) {
+			t.Errorf("got content length %d, %d bytes", r.ContentLength, len(content))
+		}
+		if expired {
+			fmt.Fprint(w, `{"errcode":42001,"errmsg":"access_token expired"}`)
+			return
+		}
+		fmt.Fprint(w, `{"errcode":0,"type":"file","media_id":"m"}`)
+	})
+
+	// 隐藏 bytes.Reader 的 ReadAt 和 Seek 方法
+	result, err := c.Media.Upload(MediaTypeFile, "a.txt", struct{ io.Reader }{bytes.NewReader(data)})
+	if err != nil || result.MediaID != "m" {
+		t.Fatalf("got %+v, %v", result, err)
+	}
+
+	expired = true
+	_, err = c.Media.Upload(MediaTypeFile, "a.txt", struct{ io.Reader }{bytes.NewReader(data)})
+	if !errors.Is(err, ErrBodyNotReplayable) {
+		t.Fatalf("got %v, want ErrBodyNotReplayable", err)
+	}
+}
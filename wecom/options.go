@@ -1,8 +1,11 @@
 package wecom
 
-import "net/http"
+import (
+	"net/http"
+	"time"
+)
 
-// 目前支持两个 options，hostURL 和 HTTP Client
+// 目前支持的 options：hostURL、HTTP Client、打印 payload、失败重试次数、限流、应用 ID
 type options interface {
 	applyOption(*Client)
 }
@@ -62,3 +65,35 @@ func NewWithMaxRetryTimesOption(maxRetryTimes uint) options {
 		maxRetryTimes: int(maxRetryTimes),
 	}
 }
+
+type optRateLimit struct {
+	n   int
+	per time.Duration
+}
+
+func (o *optRateLimit) applyOption(client *Client) {
+	client.limiter = newRateLimiter(o.n, o.per)
+}
+
+// NewWithRateLimitOption 限制每 per 时间内最多调用 n 次 API
+func NewWithRateLimitOption(n uint, per time.Duration) options {
+	return &optRateLimit{
+		n:   int(n),
+		per: per,
+	}
+}
+
+type optAgentID struct {
+	agentID int
+}
+
+func (o *optAgentID) applyOption(client *Client) {
+	client.agentID = o.agentID
+}
+
+// NewWithAgentIDOption 设置默认的应用 ID，发送应用消息等接口未指定应用 ID 时使用
+func NewWithAgentIDOption(agentID int) options {
+	return &optAgentID{
+		agentID: agentID,
+	}
+}
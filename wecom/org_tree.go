@@ -0,0 +1,290 @@
+// org_tree.go 根据部门列表和成员列表在内存中构建组织架构树
+package wecom
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+)
+
+// OrgNode 组织架构树中的一个部门
+type OrgNode struct {
+	Department
+	Parent   *OrgNode   `json:"-"`
+	Children []*OrgNode `json:"children,omitempty"`
+	// 直属成员，不包含子部门的成员
+	Members []*User `json:"members,omitempty"`
+}
+
+// OrgTree 组织架构树，构建完成后只读，可并发访问
+type OrgTree struct {
+	roots   []*OrgNode
+	nodes   map[int]*OrgNode
+	members map[string]*User
+	// 上下级关系成环、被作为根节点处理的部门
+	cyclic []int
+}
+
+// NewOrgTree 根据部门列表构建组织架构树，members 为可选的成员列表
+// 父部门不在 departments 中的部门视为根节点；同一部门或成员出现多次时以第一次出现的为准
+// 上下级关系成环时，环中 ID 最小的部门视为根节点，可通过 CyclicDepartments 获取
+// 成员会被复制，之后修改 members 不影响组织架构树
+func NewOrgTree(departments []Department, members ...[]User) *OrgTree {
+	t := &OrgTree{
+		nodes:   make(map[int]*OrgNode, len(departments)),
+		members: make(map[string]*User),
+	}
+	nodes := make([]*OrgNode, 0, len(departments))
+	for k := range departments {
+		if _, ok := t.nodes[departments[k].ID]; ok {
+			continue
+		}
+		node := &OrgNode{Department: departments[k]}
+		t.nodes[node.ID] = node
+		nodes = append(nodes, node)
+	}
+	for _, node := range nodes {
+		if parent, ok := t.nodes[node.Parentid]; ok && parent != node {
+			node.Parent = parent
+		}
+	}
+	t.breakCycles(nodes)
+	for _, node := range nodes {
+		if node.Parent == nil {
+			t.roots = append(t.roots, node)
+			continue
+		}
+		node.Parent.Children = append(node.Parent.Children, node)
+	}
+
+	for _, list := range members {
+		for k := range list {
+			if _, ok := t.members[list[k].Userid]; ok {
+				continue
+			}
+			user := list[k].Clone()
+			t.members[user.Userid] = user
+			for _, id := range user.Department {
+				if node, ok := t.nodes[id]; ok {
+					node.Members = append(node.Members, user)
+				}
+			}
+		}
+	}
+
+	// order 值大的排序靠前
+	sortNodes(t.roots)
+	for _, node := range t.nodes {
+		sortNodes(node.Children)
+	}
+	return t
+}
+
+// breakCycles 找出上下级关系成环的部门，将环中 ID 最小的部门作为根节点
+func (t *OrgTree) breakCycles(nodes []*OrgNode) {
+	// 0 未访问，1 访问中，2 已确认不在环中或环已处理
+	state := make(map[*OrgNode]int, len(nodes))
+	for _, node := range nodes {
+		var path []*OrgNode
+		n := node
+		for n != nil && state[n] == 0 {
+			state[n] = 1
+			path = append(path, n)
+			n = n.Parent
+		}
+		if n != nil && state[n] == 1 {
+			// n 在本次路径中再次出现，path 中从 n 开始的部分构成环
+			var root *OrgNode
+			for k := len(path) - 1; k >= 0; k-- {
+				if root == nil || path[k].ID < root.ID {
+					root = path[k]
+				}
+				if path[k] == n {
+					break
+				}
+			}
+			root.Parent = nil
+			t.cyclic = append(t.cyclic, root.ID)
+		}
+		for _, p := range path {
+			state[p] = 2
+		}
+	}
+	sort.Ints(t.cyclic)
+}
+
+// CyclicDepartments 返回上下级关系成环、被作为根节点处理的部门 ID，数据正常时为空
+func (t *OrgTree) CyclicDepartments() []int {
+	return t.cyclic
+}
+
+func sortNodes(nodes []*OrgNode) {
+	sort.SliceStable(nodes, func(i, j int) bool {
+		if nodes[i].Order != nodes[j].Order {
+			return nodes[i].Order > nodes[j].Order
+		}
+		return nodes[i].ID < nodes[j].ID
+	})
+}
+
+// OrgTree 通讯录：获取 departmentID 及其子部门构成的组织架构树
+// withMembers 为 true 时同时获取成员，获取方式参考 CurrentDirectory
+func (b *addressService) OrgTree(departmentID int, withMembers bool) (*OrgTree, error) {
+	if withMembers {
+		dir, err := b.CurrentDirectory(departmentID)
+		if err != nil {
+			return nil, err
+		}
+		return NewOrgTree(dir.Departments, dir.Users), nil
+	}
+	departments, err := b.DepartmentList(departmentID)
+	if err != nil {
+		return nil, err
+	}
+	if err = checkResponse(departments); err != nil {
+		return nil, err
+	}
+	return NewOrgTree(departments.Department), nil
+}
+
+// Roots 返回所有根部门
+func (t *OrgTree) Roots() []*OrgNode {
+	return t.roots
+}
+
+// Node 返回指定部门
+func (t *OrgTree) Node(departmentID int) (*OrgNode, bool) {
+	node, ok := t.nodes[departmentID]
+	return node, ok
+}
+
+// Member 返回指定成员
+func (t *OrgTree) Member(userID string) (*User, bool) {
+	user, ok := t.members[userID]
+	return user, ok
+}
+
+// Parent 返回父部门，根部门或部门不存在时返回 nil
+func (t *OrgTree) Parent(departmentID int) *OrgNode {
+	if node, ok := t.nodes[departmentID]; ok {
+		return node.Parent
+	}
+	return nil
+}
+
+// Children 返回直属子部门
+func (t *OrgTree) Children(departmentID int) []*OrgNode {
+	if node, ok := t.nodes[departmentID]; ok {
+		return node.Children
+	}
+	return nil
+}
+
+// Ancestors 返回所有上级部门，从父部门开始，直到根部门
+func (t *OrgTree) Ancestors(departmentID int) []*OrgNode {
+	node, ok := t.nodes[departmentID]
+	if !ok {
+		return nil
+	}
+	var ancestors []*OrgNode
+	// 防止数据异常（部门的上下级关系成环）导致死循环
+	visited := map[int]bool{node.ID: true}
+	for p := node.Parent; p != nil && !visited[p.ID]; p = p.Parent {
+		visited[p.ID] = true
+		ancestors = append(ancestors, p)
+	}
+	return ancestors
+}
+
+// Descendants 返回所有下级部门（不包含自身），按深度优先顺序
+func (t *OrgTree) Descendants(departmentID int) []*OrgNode {
+	node, ok := t.nodes[departmentID]
+	if !ok {
+		return nil
+	}
+	var descendants []*OrgNode
+	// 防止数据异常（部门的上下级关系成环）导致死循环
+	visited := map[int]bool{node.ID: true}
+	var walk func(n *OrgNode)
+	walk = func(n *OrgNode) {
+		for _, child := range n.Children {
+			if visited[child.ID] {
+				continue
+			}
+			visited[child.ID] = true
+			descendants = append(descendants, child)
+			walk(child)
+		}
+	}
+	walk(node)
+	return descendants
+}
+
+// Path 返回从根部门到该部门的名称路径，如 "Company/R&D/Backend"
+func (t *OrgTree) Path(departmentID int) string {
+	node, ok := t.nodes[departmentID]
+	if !ok {
+		return ""
+	}
+	ancestors := t.Ancestors(departmentID)
+	names := make([]string, 0, len(ancestors)+1)
+	for k := len(ancestors) - 1; k >= 0; k-- {
+		names = append(names, ancestors[k].Name)
+	}
+	names = append(names, node.Name)
+	return strings.Join(names, "/")
+}
+
+// Members 返回部门成员，recursive 为 true 时包含所有下级部门的成员（已去重）
+func (t *OrgTree) Members(departmentID int, recursive bool) []*User {
+	node, ok := t.nodes[departmentID]
+	if !ok {
+		return nil
+	}
+	if !recursive {
+		return node.Members
+	}
+	seen := make(map[string]struct{})
+	var users []*User
+	for _, n := range append([]*OrgNode{node}, t.Descendants(departmentID)...) {
+		for _, user := range n.Members {
+			if _, ok := seen[user.Userid]; ok {
+				continue
+			}
+			seen[user.Userid] = struct{}{}
+			users = append(users, user)
+		}
+	}
+	return users
+}
+
+// Leaders 返回部门负责人，依据成员的 IsLeaderInDept 字段
+func (t *OrgTree) Leaders(departmentID int) []*User {
+	node, ok := t.nodes[departmentID]
+	if !ok {
+		return nil
+	}
+	var leaders []*User
+	for _, user := range node.Members {
+		if user.IsLeader(departmentID) {
+			leaders = append(leaders, user)
+		}
+	}
+	return leaders
+}
+
+// IsLeader 判断成员是否为指定部门的负责人
+// IsLeaderInDept 与 Department 一一对应，1 表示为该部门负责人
+func (u *User) IsLeader(departmentID int) bool {
+	for k, id := range u.Department {
+		if id == departmentID && k < len(u.IsLeaderInDept) && u.IsLeaderInDept[k] == 1 {
+			return true
+		}
+	}
+	return false
+}
+
+// MarshalJSON 以嵌套结构导出整棵树
+func (t *OrgTree) MarshalJSON() ([]byte, error) {
+	return json.Marshal(t.roots)
+}
@@ -0,0 +1,82 @@
+package wecom
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestOrgTree(t *testing.T) {
+	tree := NewOrgTree([]Department{
+		{ID: 1, Name: "Company"},
+		{ID: 2, Name: "R&D", Parentid: 1, Order: 2},
+		{ID: 3, Name: "Sales", Parentid: 1, Order: 1},
+		{ID: 4, Name: "Backend", Parentid: 2},
+	}, []User{
+		{Userid: "a", Department: []int{4}, IsLeaderInDept: []int{1}},
+		{Userid: "b", Department: []int{2, 4}},
+		{Userid: "c", Department: []int{3}},
+	})
+	if got := tree.Path(4); got != "Company/R&D/Backend" {
+		t.Fatalf("got path %q", got)
+	}
+	if got := tree.Descendants(1); len(got) != 3 || got[0].ID != 2 || got[1].ID != 4 || got[2].ID != 3 {
+		t.Fatalf("got %d descendants", len(got))
+	}
+	if got := tree.Members(2, true); len(got) != 2 {
+		t.Fatalf("got %d members, want 2", len(got))
+	}
+	if got := tree.Leaders(4); len(got) != 1 || got[0].Userid != "a" {
+		t.Fatalf("got leaders %v", got)
+	}
+}
+
+func TestOrgTreeCycle(t *testing.T) {
+	tree := NewOrgTree([]Department{
+		{ID: 1, Name: "Company"},
+		{ID: 2, Name: "A", Parentid: 3},
+		{ID: 3, Name: "B", Parentid: 2},
+	}, []User{
+		{Userid: "a", Department: []int{2}},
+		{Userid: "b", Department: []int{3}},
+	})
+	if got := tree.Descendants(2); len(got) != 1 || got[0].ID != 3 {
+		t.Fatalf("got %d descendants, want [3]", len(got))
+	}
+	if got := tree.Members(2, true); len(got) != 2 {
+		t.Fatalf("got %d members, want 2", len(got))
+	}
+	if got := tree.Ancestors(3); len(got) != 1 || got[0].ID != 2 {
+		t.Fatalf("got %d ancestors, want [2]", len(got))
+	}
+	if got := tree.CyclicDepartments(); len(got) != 1 || got[0] != 2 {
+		t.Fatalf("got cyclic departments %v, want [2]", got)
+	}
+	if roots := tree.Roots(); len(roots) != 2 {
+		t.Fatalf("got %d roots, want 2", len(roots))
+	}
+	data, err := json.Marshal(tree)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"name":"B"`) {
+		t.Fatalf("cyclic departments missing from export: %s", data)
+	}
+}
+
+func TestOrgTreeDuplicatesAndCopies(t *testing.T) {
+	users := []User{{Userid: "a", Name: "A", Department: []int{2}}}
+	tree := NewOrgTree([]Department{
+		{ID: 1, Name: "Company"},
+		{ID: 2, Name: "R&D", Parentid: 1},
+		{ID: 2, Name: "R&D again", Parentid: 1},
+	}, users)
+	if got := tree.Children(1); len(got) != 1 || got[0].Name != "R&D" {
+		t.Fatalf("got %d children", len(got))
+	}
+	users[0].Name = "changed"
+	users[0].Department[0] = 1
+	if u, _ := tree.Member("a"); u.Name != "A" || u.Department[0] != 2 {
+		t.Fatalf("tree member aliases input: %+v", u)
+	}
+}
@@ -0,0 +1,104 @@
+package wecom
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// 令牌桶限流器，用于控制调用企业微信 API 的频率
+// 频率限制：https://open.work.weixin.qq.com/api/doc/90000/90139/90312
+type rateLimiter struct {
+	mu sync.Mutex
+	// 每产生一个令牌所需的时间
+	interval time.Duration
+	// 桶容量，即允许的突发请求数
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// newRateLimiter 表示每 per 时间内最多允许 n 次请求
+func newRateLimiter(n int, per time.Duration) *rateLimiter {
+	if n < 1 {
+		n = 1
+	}
+	return &rateLimiter{
+		interval: per / time.Duration(n),
+		burst:    float64(n),
+		tokens:   float64(n),
+	}
+}
+
+// reserve 预占一个令牌，返回需要等待的时间
+func (r *rateLimiter) reserve() time.Duration {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	if !r.last.IsZero() && r.interval > 0 {
+		r.tokens += float64(now.Sub(r.last)) / float64(r.interval)
+		if r.tokens > r.burst {
+			r.tokens = r.burst
+		}
+	}
+	r.last = now
+	r.tokens--
+	if r.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-r.tokens * float64(r.interval))
+}
+
+// wait 阻塞直到拿到令牌，ctx 可以为 nil
+func (r *rateLimiter) wait(ctx context.Context) error {
+	return sleepContext(ctx, r.reserve())
+}
+
+// sleepContext 休眠 d，期间 ctx 被取消则提前返回 ctx.Err()，ctx 可以为 nil
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		if ctx != nil {
+			return ctx.Err()
+		}
+		return nil
+	}
+	if ctx == nil {
+		time.Sleep(d)
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+const (
+	// 触发频率限制（45009）后的最大重试次数
+	freqLimitMaxRetry = 5
+)
+
+// 触发频率限制后的初始等待时间，之后每次翻倍
+var freqLimitBackoff = time.Second
+
+// retryOnFreqLimit 调用 fn，fn 返回频率超限错误时按指数退避重试，其他错误直接返回，ctx 可以为 nil
+func retryOnFreqLimit(ctx context.Context, fn func() error) error {
+	backoff := freqLimitBackoff
+	for retry := 0; ; retry++ {
+		if ctx != nil && ctx.Err() != nil {
+			return ctx.Err()
+		}
+		err := fn()
+		if err == nil || !isFreqLimitErr(err) || retry >= freqLimitMaxRetry {
+			return err
+		}
+		if err = sleepContext(ctx, backoff); err != nil {
+			return err
+		}
+		backoff *= 2
+	}
+}
@@ -0,0 +1,40 @@
+package wecom
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRetryOnFreqLimit(t *testing.T) {
+	defer func(d time.Duration) { freqLimitBackoff = d }(freqLimitBackoff)
+	freqLimitBackoff = time.Millisecond
+
+	calls := 0
+	err := retryOnFreqLimit(context.Background(), func() error {
+		calls++
+		if calls < 3 {
+			return &Error{ErrCode: errCodeAPIFreqLimit}
+		}
+		return nil
+	})
+	if err != nil || calls != 3 {
+		t.Fatalf("got err %v after %d calls, want nil after 3", err, calls)
+	}
+
+	calls = 0
+	err = retryOnFreqLimit(nil, func() error {
+		calls++
+		return &Error{ErrCode: errCodeAPIFreqLimit}
+	})
+	if !isFreqLimitErr(err) || calls != freqLimitMaxRetry+1 {
+		t.Fatalf("got err %v after %d calls", err, calls)
+	}
+
+	calls = 0
+	other := errors.New("other")
+	if err = retryOnFreqLimit(nil, func() error { calls++; return other }); err != other || calls != 1 {
+		t.Fatalf("got err %v after %d calls, want other after 1", err, calls)
+	}
+}
@@ -0,0 +1,230 @@
+// robot.go 对应的是 https://developer.work.weixin.qq.com/document/path/91770 文档内容
+// 群机器人通过 webhook key 调用，不需要 access token
+package wecom
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+const (
+	pathWebhookSend   = "/cgi-bin/webhook/send"
+	pathWebhookUpload = "/cgi-bin/webhook/upload_media"
+
+	// 每个机器人发送的消息不能超过 20 条/分钟
+	robotRateLimit = 20
+	// 图片（base64 编码前）最大不能超过 2M
+	robotMaxImageSize = 2 << 20
+	// 上传的文件大小在 5B ~ 20M 之间
+	robotMinFileSize = 5
+	robotMaxFileSize = 20 << 20
+)
+
+// Robot 群机器人
+type Robot struct {
+	key string
+	// 复用 Client 的 host、HTTP Client、重试次数等配置，不会获取 access token
+	client  *Client
+	limiter *rateLimiter
+	ctx     context.Context
+}
+
+// NewRobot 使用 webhook 地址中的 key 创建群机器人，支持 NewWithHostOption 等 options
+func NewRobot(key string, opts ...options) (*Robot, error) {
+	if key == "" {
+		return nil, errors.New("robot key is required")
+	}
+	c, err := NewClient("", "", opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &Robot{
+		key:     key,
+		client:  c,
+		limiter: newRateLimiter(robotRateLimit, time.Minute),
+	}, nil
+}
+
+func (r *Robot) WithContext(ctx context.Context) *Robot {
+	return &Robot{
+		key:     r.key,
+		client:  r.client,
+		limiter: r.limiter,
+		ctx:     ctx,
+	}
+}
+
+// RobotMessage 群机器人消息，根据 MsgType 只有对应的字段有效
+type RobotMessage struct {
+	MsgType      MsgType       `json:"msgtype"`
+	Text         *RobotText    `json:"text,omitempty"`
+	Markdown     *MarkdownMsg  `json:"markdown,omitempty"`
+	Image        *RobotImage   `json:"image,omitempty"`
+	News         *NewsMsg      `json:"news,omitempty"`
+	File         *MediaMsg     `json:"file,omitempty"`
+	TemplateCard *TemplateCard `json:"template_card,omitempty"`
+}
+
+// RobotText 文本消息，可通过 userid 或手机号提醒群成员，"@all" 表示提醒所有人
+type RobotText struct {
+	Content             string   `json:"content"`
+	MentionedList       []string `json:"mentioned_list,omitempty"`
+	MentionedMobileList []string `json:"mentioned_mobile_list,omitempty"`
+}
+
+type RobotResp struct {
+	baseResponse
+}
+
+type RobotImage struct {
+	Base64 string `json:"base64"`
+	MD5    string `json:"md5"`
+}
+
+// SendText 发送文本消息
+func (r *Robot) SendText(content string, mentionedList, mentionedMobileList []string) (*RobotResp, error) {
+	return r.Send(&RobotMessage{
+		MsgType: MsgTypeText,
+		Text:    &RobotText{Content: content, MentionedList: mentionedList, MentionedMobileList: mentionedMobileList},
+	})
+}
+
+// SendMarkdown 发送 markdown 消息
+func (r *Robot) SendMarkdown(content string) (*RobotResp, error) {
+	return r.Send(&RobotMessage{MsgType: MsgTypeMarkdown, Markdown: &MarkdownMsg{Content: content}})
+}
+
+// SendImage 发送图片消息，data 为原始图片内容（jpg、png），base64 与 md5 自动计算
+func (r *Robot) SendImage(data []byte) (*RobotResp, error) {
+	if len(data) > robotMaxImageSize {
+		return nil, fmt.Errorf("image size %d exceeds %d bytes", len(data), robotMaxImageSize)
+	}
+	sum := md5.Sum(data)
+	return r.Send(&RobotMessage{
+		MsgType: MsgTypeImage,
+		Image:   &RobotImage{Base64: base64.StdEncoding.EncodeToString(data), MD5: hex.EncodeToString(sum[:])},
+	})
+}
+
+// SendNews 发送图文消息，支持 1 ~ 8 条图文
+func (r *Robot) SendNews(articles ...NewsArticle) (*RobotResp, error) {
+	if len(articles) == 0 || len(articles) > 8 {
+		return nil, fmt.Errorf("articles must have 1 ~ 8 items, got %d", len(articles))
+	}
+	return r.Send(&RobotMessage{MsgType: MsgTypeNews, News: &NewsMsg{Articles: articles}})
+}
+
+// SendFile 发送文件消息，mediaID 通过 UploadMedia 获取
+func (r *Robot) SendFile(mediaID string) (*RobotResp, error) {
+	return r.Send(&RobotMessage{MsgType: MsgTypeFile, File: &MediaMsg{MediaID: mediaID}})
+}
+
+// SendTemplateCard 发送模板卡片消息，群机器人仅支持文本通知型和图文展示型
+func (r *Robot) SendTemplateCard(card *TemplateCard) (*RobotResp, error) {
+	if card.CardType != CardTypeTextNotice && card.CardType != CardTypeNewsNotice {
+		return nil, fmt.Errorf("card type %s is not supported by robot", card.CardType)
+	}
+	if err := card.Validate(); err != nil {
+		return nil, err
+	}
+	return r.Send(&RobotMessage{MsgType: MsgTypeTemplateCard, TemplateCard: card})
+}
+
+// Send 群机器人：发送消息
+// 参考链接：https://developer.work.weixin.qq.com/document/path/91770
+func (r *Robot) Send(msg *RobotMessage) (result *RobotResp, err error) {
+	failCount := -1
+	// 默认尝试一次，即不进行失败重试
+	for failCount < r.client.maxRetryTimes {
+		failCount++
+		var req *http.Request
+		req, err = r.client.newRequest(http.MethodPost, pathWebhookSend, msg, "key="+r.key)
+		if err != nil {
+			continue // 如果循环结束，则会返回该 err
+		}
+		result = new(RobotResp)
+		err = r.do(req, result, r.limiter)
+		if err != nil {
+			continue // 如果循环结束，则会返回该 err
+		}
+		// 成功，return
+		return result, nil
+	}
+	// 失败，返回最后一次请求的 err
+	return nil, err
+}
+
+type RobotMediaResp struct {
+	baseResponse
+	Type      string `json:"type"`
+	MediaID   string `json:"media_id"`
+	CreatedAt string `json:"created_at"`
+}
+
+// UploadMedia 群机器人：上传文件，返回的 media_id 三天内有效，仅该机器人可以使用
+// 上传与发送消息的频率限制相互独立，上传不占用发送消息的配额
+// 参考链接：https://developer.work.weixin.qq.com/document/path/91770#文件上传接口
+func (r *Robot) UploadMedia(filename string, reader io.Reader) (result *RobotMediaResp, err error) {
+	file, err := NewMultipartFileFromReader(filename, reader, robotMaxFileSize)
+	if err != nil {
+		return nil, err
+	}
+	if file.Size >= 0 && file.Size < robotMinFileSize {
+		return nil, fmt.Errorf("file size must be between %d and %d bytes", robotMinFileSize, robotMaxFileSize)
+	}
+
+	failCount := -1
+	// 默认尝试一次，即不进行失败重试
+	for failCount < r.client.maxRetryTimes {
+		failCount++
+		var req *http.Request
+		req, err = r.client.newRequest(http.MethodPost, pathWebhookUpload, file, "key="+r.key, "type=file")
+		if err != nil {
+			continue // 如果循环结束，则会返回该 err
+		}
+		result = new(RobotMediaResp)
+		err = r.do(req, result, nil)
+		if err != nil {
+			continue // 如果循环结束，则会返回该 err
+		}
+		// 成功，return
+		return result, nil
+	}
+	// 失败，返回最后一次请求的 err
+	return nil, err
+}
+
+// do 发送请求，群机器人不需要 access token，也不需要刷新 token
+// limiter 为 nil 时不限流
+func (r *Robot) do(req *http.Request, result iBaseResponse, limiter *rateLimiter) error {
+	if r.ctx != nil {
+		req = req.WithContext(r.ctx)
+	}
+	if limiter != nil {
+		if err := limiter.wait(r.ctx); err != nil {
+			return err
+		}
+	}
+	resp, err := r.client.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if err = json.Unmarshal(data, result); err != nil {
+		return fmt.Errorf("response body: %s, unmarhsal err: %v", string(data), err)
+	}
+	return nil
+}
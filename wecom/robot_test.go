@@ -0,0 +1,121 @@
+package wecom
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestRobot 返回请求 httptest 的群机器人，并检查每次请求都带有 key 且不带 access_token
+func newTestRobot(t *testing.T, handler http.HandlerFunc) *Robot {
+	t.Helper()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("key") != "k" || r.URL.Query().Get("access_token") != "" {
+			t.Errorf("unexpected query: %s", r.URL.RawQuery)
+		}
+		handler(w, r)
+	}))
+	t.Cleanup(ts.Close)
+	robot, err := NewRobot("k", NewWithHostOption(ts.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return robot
+}
+
+func TestRobotSend(t *testing.T) {
+	var body map[string]interface{}
+	robot := newTestRobot(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != pathWebhookSend {
+			t.Errorf("unexpected request: %s", r.URL.Path)
+		}
+		body = nil
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		fmt.Fprint(w, `{"errcode":0,"errmsg":"ok"}`)
+	})
+
+	if _, err := robot.SendText("hi", []string{"@all"}, []string{"13800000000"}); err != nil {
+		t.Fatal(err)
+	}
+	text, _ := body["text"].(map[string]interface{})
+	if body["msgtype"] != "text" || text["content"] != "hi" || len(text["mentioned_list"].([]interface{})) != 1 {
+		t.Fatalf("got %v", body)
+	}
+
+	data := []byte("\x89PNG fake image")
+	if _, err := robot.SendImage(data); err != nil {
+		t.Fatal(err)
+	}
+	sum := md5.Sum(data)
+	image, _ := body["image"].(map[string]interface{})
+	if image["base64"] != base64.StdEncoding.EncodeToString(data) || image["md5"] != hex.EncodeToString(sum[:]) {
+		t.Fatalf("got %v", body)
+	}
+
+	if _, err := robot.SendImage(make([]byte, robotMaxImageSize+1)); err == nil {
+		t.Error("want error for large image")
+	}
+	if _, err := robot.SendNews(); err == nil {
+		t.Error("want error for empty news")
+	}
+	card := NewButtonInteractionCard("task", "t", "", CardButton{Text: "ok", Key: "k"})
+	if _, err := robot.SendTemplateCard(card); err == nil {
+		t.Error("want error for unsupported card type")
+	}
+}
+
+func TestRobotUpload(t *testing.T) {
+	robot := newTestRobot(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != pathWebhookUpload || r.URL.Query().Get("type") != "file" {
+			t.Errorf("unexpected request: %s", r.URL)
+		}
+		_, params, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		part, err := multipart.NewReader(r.Body, params["boundary"]).NextPart()
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, _ := ioutil.ReadAll(part)
+		if part.FileName() != "a.txt" || string(content) != "hello world" {
+			t.Errorf("got file %s with %q", part.FileName(), content)
+		}
+		fmt.Fprint(w, `{"errcode":0,"type":"file","media_id":"m"}`)
+	})
+	result, err := robot.UploadMedia("a.txt", strings.NewReader("hello world"))
+	if err != nil || result.MediaID != "m" {
+		t.Fatalf("got %+v, %v", result, err)
+	}
+	if _, err = robot.UploadMedia("a.txt", bytes.NewReader([]byte("1234"))); err == nil {
+		t.Error("want error for file smaller than 5 bytes")
+	}
+}
+
+func TestRobotLimiter(t *testing.T) {
+	robot := newTestRobot(t, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"errcode":0,"media_id":"m"}`)
+	})
+	robot.limiter = newRateLimiter(1, time.Hour)
+	if _, err := robot.SendMarkdown("x"); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	// 上传不占用发送消息的配额
+	if _, err := robot.WithContext(ctx).UploadMedia("a.txt", strings.NewReader("hello")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := robot.WithContext(ctx).SendMarkdown("x"); err != context.DeadlineExceeded {
+		t.Fatalf("got %v, want context.DeadlineExceeded", err)
+	}
+}
@@ -14,6 +14,12 @@ func (s *service) doRequest(req *http.Request, result iBaseResponse) (err error)
 	if s.ctx != nil {
 		req = req.WithContext(s.ctx)
 	}
+	if s.client.limiter != nil {
+		err = s.client.limiter.wait(s.ctx)
+		if err != nil {
+			return err
+		}
+	}
 	err = s.client.do(req, result)
 	if err != nil {
 		if s.ctx != nil {
@@ -27,3 +33,28 @@ func (s *service) doRequest(req *http.Request, result iBaseResponse) (err error)
 	}
 	return nil
 }
+
+// doStreamRequest 与 doRequest 类似，用于文件下载类接口
+func (s *service) doStreamRequest(req *http.Request) (stream *StreamResponse, err error) {
+	if s.ctx != nil {
+		req = req.WithContext(s.ctx)
+	}
+	if s.client.limiter != nil {
+		err = s.client.limiter.wait(s.ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+	stream, err = s.client.doStream(req)
+	if err != nil {
+		if s.ctx != nil {
+			select {
+			case <-s.ctx.Done():
+				return nil, s.ctx.Err()
+			default:
+			}
+		}
+		return nil, err
+	}
+	return stream, nil
+}
@@ -1,11 +1,14 @@
 package wecom
 
 import (
+	"bufio"
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"mime"
 	"net/http"
 	"net/url"
 	"strings"
@@ -37,6 +40,40 @@ func (b baseResponse) GetErrMsg() string {
 	return b.ErrMsg
 }
 
+// 全局错误码：https://open.work.weixin.qq.com/api/doc/90000/90139/90313
+const (
+	// 接口调用超过限制
+	errCodeAPIFreqLimit = 45009
+	// API 接口无权限调用
+	errCodeAPIForbidden = 48002
+	// 指定的成员/部门/标签参数无权限
+	errCodeNoPrivilege = 60011
+)
+
+// Error 企业微信接口返回的业务错误，即 errcode 不为 0 的情况
+type Error struct {
+	ErrCode int
+	ErrMsg  string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("wecom: errcode: %d, errmsg: %s", e.ErrCode, e.ErrMsg)
+}
+
+// checkResponse 将 errcode 不为 0 的 Response 转换为 *Error
+func checkResponse(result iBaseResponse) error {
+	if result.GetErrCode() != 0 {
+		return &Error{ErrCode: result.GetErrCode(), ErrMsg: result.GetErrMsg()}
+	}
+	return nil
+}
+
+// isFreqLimitErr 判断是否为接口调用频率超限
+func isFreqLimitErr(err error) bool {
+	var e *Error
+	return errors.As(err, &e) && e.ErrCode == errCodeAPIFreqLimit
+}
+
 // 客户端
 type Client struct {
 	// 关于 access token 的生成可参考：https://work.weixin.qq.com/api/doc/90000/90135/91039
@@ -53,6 +90,8 @@ type Client struct {
 
 	// lock，主要用于更新 token
 	mu *sync.RWMutex
+	// 保证同一时刻只有一个 goroutine 刷新 token
+	refreshMu *sync.Mutex
 
 	// HTTP client
 	client *http.Client
@@ -61,11 +100,21 @@ type Client struct {
 	printPayload bool
 	// 默认为 0，即不进行重试
 	maxRetryTimes int
+	// 限流器，默认为 nil，即不限流
+	limiter *rateLimiter
+	// 应用 ID，发送应用消息等接口需要，也可以在调用时指定
+	agentID int
 
 	comm service
 
-	Basic   *basicService
-	Address *addressService
+	Basic      *basicService
+	Address    *addressService
+	Message    *messageService
+	AppChat    *appChatService
+	LinkedCorp *linkedCorpService
+	Media      *mediaService
+	Agent      *agentService
+	Auth       *authService
 }
 
 func (c Client) String() string {
@@ -78,6 +127,7 @@ func NewClient(enterpriseID, agentSecret string, opts ...options) (c *Client, er
 		agentSecret:  agentSecret,
 		host:         defaultHost,
 		mu:           &sync.RWMutex{},
+		refreshMu:    &sync.Mutex{},
 		client:       &http.Client{},
 	}
 
@@ -95,11 +145,18 @@ func NewClient(enterpriseID, agentSecret string, opts ...options) (c *Client, er
 	c.comm.client = c
 	c.Basic = (*basicService)(&c.comm)
 	c.Address = (*addressService)(&c.comm)
+	c.Message = (*messageService)(&c.comm)
+	c.AppChat = (*appChatService)(&c.comm)
+	c.LinkedCorp = (*linkedCorpService)(&c.comm)
+	c.Media = (*mediaService)(&c.comm)
+	c.Agent = (*agentService)(&c.comm)
+	c.Auth = (*authService)(&c.comm)
 
 	return c, nil
 }
 
 // queryString 支持两种写法："name=3ks&age=18" 或者 "name=guan", "age=18"
+// body 为 *MultipartFile 时使用 multipart/form-data 格式，否则使用 JSON 格式
 func (c *Client) newRequest(httpMethod, path string, body interface{}, queryString ...string) (request *http.Request, err error) {
 	// base info
 	newURL := *c.hostURL
@@ -114,6 +171,22 @@ func (c *Client) newRequest(httpMethod, path string, body interface{}, queryStri
 		newURL.RawQuery = qs.Encode()
 	}
 
+	// multipart body，文件内容以流的方式发送
+	if file, ok := body.(*MultipartFile); ok {
+		if c.printPayload {
+			fmt.Printf("payload: multipart file %s, size: %d\n", file.Filename, file.Size)
+		}
+		request, err = http.NewRequest(httpMethod, newURL.String(), nil)
+		if err != nil {
+			return nil, err
+		}
+		request.Header.Set("User-Agent", "WecomGo")
+		if err = setMultipartBody(request, file); err != nil {
+			return nil, err
+		}
+		return request, nil
+	}
+
 	// body
 	var buf io.ReadWriter
 	if body != nil {
@@ -152,10 +225,23 @@ func (c *Client) newRequest(httpMethod, path string, body interface{}, queryStri
 }
 
 func (c *Client) do(req *http.Request, result iBaseResponse) (err error) {
-	for {
+	for retry := false; ; retry = true {
+		// token 过期重试时，请求体已被读取，需要使用新的请求体重新构建请求
+		if retry && req.GetBody != nil {
+			req = req.Clone(req.Context())
+			req.Body, err = req.GetBody()
+			if err != nil {
+				return err
+			}
+		}
+		var token string
 		if req.URL.Path != pathGetToken {
+			token, err = c.getAccessToken()
+			if err != nil {
+				return err
+			}
 			q := req.URL.Query()
-			q.Set("access_token", c.getAccessToken())
+			q.Set("access_token", token)
 			req.URL.RawQuery = q.Encode()
 		}
 
@@ -175,9 +261,12 @@ func (c *Client) do(req *http.Request, result iBaseResponse) (err error) {
 		if err != nil {
 			return fmt.Errorf("response body: %s, unmarhsal err: %v", string(data), err)
 		}
-		// token 已过期
-		if c.tokenExpired(result) {
-			c.Basic.refreshAccessToken()
+		// token 已过期，仅刷新并重试一次，避免 secret 无效时无限重试
+		// gettoken 本身不重试，否则会在 refreshAccessToken 持有锁时再次调用该方法
+		if req.URL.Path != pathGetToken && c.tokenExpired(result) && !retry {
+			if _, err = c.Basic.refreshAccessToken(token); err != nil {
+				return err
+			}
 			continue
 		}
 		// 请求无异常，break
@@ -185,12 +274,127 @@ func (c *Client) do(req *http.Request, result iBaseResponse) (err error) {
 	}
 }
 
+// StreamResponse 文件下载类接口的响应，调用方需要负责关闭 Body
+type StreamResponse struct {
+	Body       io.ReadCloser
+	Header     http.Header
+	StatusCode int
+	// 来自 Content-Type
+	ContentType string
+	// 来自 Content-Disposition，可能为空
+	Filename string
+	// 未知时为 -1
+	ContentLength int64
+}
+
+// 错误信息的最大长度，超过该长度的响应一定是文件内容
+const maxErrorBodySize = 64 << 10
+
+// doStream 用于文件下载类接口，响应为文件内容时以 io.ReadCloser 的形式返回，
+// 仅当企业微信返回 JSON 格式的错误信息时才进行解析
+func (c *Client) doStream(req *http.Request) (*StreamResponse, error) {
+	for retry := false; ; retry = true {
+		token, err := c.getAccessToken()
+		if err != nil {
+			return nil, err
+		}
+		q := req.URL.Query()
+		q.Set("access_token", token)
+		req.URL.RawQuery = q.Encode()
+
+		resp, err := c.client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+
+		body := bufio.NewReader(resp.Body)
+		result, data, err := parseErrorBody(resp, body)
+		if err != nil {
+			_ = resp.Body.Close()
+			return nil, err
+		}
+		// 网关、代理等返回的非 2xx 响应不是文件内容
+		if result == nil && (resp.StatusCode < 200 || resp.StatusCode > 299) {
+			snippet, _ := ioutil.ReadAll(io.LimitReader(io.MultiReader(bytes.NewReader(data), body), 512))
+			_ = resp.Body.Close()
+			return nil, fmt.Errorf("unexpected status: %s, body: %s", resp.Status, string(snippet))
+		}
+		if result == nil {
+			stream := &StreamResponse{
+				// data 为判断是否为错误信息时已读取的部分
+				Body:          &streamBody{Reader: io.MultiReader(bytes.NewReader(data), body), Closer: resp.Body},
+				Header:        resp.Header,
+				StatusCode:    resp.StatusCode,
+				ContentType:   resp.Header.Get("Content-Type"),
+				ContentLength: resp.ContentLength,
+			}
+			if _, params, err := mime.ParseMediaType(resp.Header.Get("Content-Disposition")); err == nil {
+				stream.Filename = params["filename"]
+			}
+			return stream, nil
+		}
+		_ = resp.Body.Close()
+
+		// token 已过期，仅刷新并重试一次
+		if c.tokenExpired(result) && !retry {
+			if _, err = c.Basic.refreshAccessToken(token); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if err = checkResponse(result); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("unexpected response: %s", string(data))
+	}
+}
+
+type streamBody struct {
+	io.Reader
+	io.Closer
+}
+
+// parseErrorBody 判断响应是否为 JSON 格式的错误信息
+// 只有 body 以 { 开头、不超过 maxErrorBodySize 且包含 errcode 字段时才视为错误信息，
+// 因此即使文件本身是 JSON 文本或 Content-Type 不准确也能正确区分
+// 返回的 data 为判断过程中已从 body 读取的内容
+func parseErrorBody(resp *http.Response, body *bufio.Reader) (*baseResponse, []byte, error) {
+	if resp.Header.Get("Content-Disposition") != "" {
+		return nil, nil, nil
+	}
+	if b, err := body.Peek(1); err != nil || b[0] != '{' {
+		return nil, nil, nil
+	}
+	data, err := ioutil.ReadAll(io.LimitReader(body, maxErrorBodySize+1))
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(data) > maxErrorBodySize {
+		return nil, data, nil
+	}
+	var fields map[string]json.RawMessage
+	if json.Unmarshal(data, &fields) != nil {
+		return nil, data, nil
+	}
+	if _, ok := fields["errcode"]; !ok {
+		return nil, data, nil
+	}
+	result := new(baseResponse)
+	if err = json.Unmarshal(data, result); err != nil {
+		return nil, data, nil
+	}
+	return result, data, nil
+}
+
 // 获取 token，如果 token 无效，则调用 API 获取 token
-func (c *Client) getAccessToken() string {
-	if c.token == "" {
-		c.Basic.refreshAccessToken()
+func (c *Client) getAccessToken() (string, error) {
+	c.mu.RLock()
+	token := c.token
+	c.mu.RUnlock()
+	if token == "" {
+		return c.Basic.refreshAccessToken("")
 	}
-	return c.token
+	return token, nil
 }
 
 // 判断错误码是否为 token 已过期
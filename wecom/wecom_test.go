@@ -0,0 +1,204 @@
+package wecom
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// testServer 模拟企业微信 API，gettoken 接口固定返回 token "tk"，其余请求交给 handler 处理
+type testServer struct {
+	*httptest.Server
+	tokenCalls int32
+}
+
+func newTestClient(t *testing.T, handler http.HandlerFunc, opts ...options) (*Client, *testServer) {
+	t.Helper()
+	ts := &testServer{}
+	ts.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == pathGetToken {
+			atomic.AddInt32(&ts.tokenCalls, 1)
+			fmt.Fprint(w, `{"errcode":0,"access_token":"tk","expires_in":7200}`)
+			return
+		}
+		handler(w, r)
+	}))
+	t.Cleanup(ts.Close)
+	c, err := NewClient("corp", "secret", append([]options{NewWithHostOption(ts.URL)}, opts...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c, ts
+}
+
+func TestConcurrentTokenRefresh(t *testing.T) {
+	c, ts := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"errcode":0,"userid":"u-%s"}`, r.URL.Query().Get("access_token"))
+	})
+	identities := make([]Identity, 64)
+	for i := range identities {
+		identities[i] = MobileIdentity(fmt.Sprint(13800000000 + i))
+	}
+	results := c.Address.ResolveUserIDs(context.Background(), identities, 8)
+	for _, r := range results {
+		if r.Err != nil || r.Userid != "u-tk" {
+			t.Fatalf("unexpected result: %+v", r)
+		}
+	}
+	if n := atomic.LoadInt32(&ts.tokenCalls); n != 1 {
+		t.Fatalf("gettoken called %d times, want 1", n)
+	}
+}
+
+func TestTokenExpiredRefreshOnce(t *testing.T) {
+	c, ts := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("access_token") != "tk" {
+			fmt.Fprint(w, `{"errcode":42001,"errmsg":"access_token expired"}`)
+			return
+		}
+		fmt.Fprint(w, `{"errcode":0,"userid":"u"}`)
+	})
+	c.token = "stale"
+	identities := make([]Identity, 16)
+	for i := range identities {
+		identities[i] = OpenIDIdentity(fmt.Sprint("o", i))
+	}
+	for _, r := range c.Address.ResolveUserIDs(context.Background(), identities, 8) {
+		if r.Err != nil || r.Userid != "u" {
+			t.Fatalf("unexpected result: %+v", r)
+		}
+	}
+	if n := atomic.LoadInt32(&ts.tokenCalls); n != 1 {
+		t.Fatalf("gettoken called %d times, want 1", n)
+	}
+}
+
+func TestDoStreamTokenExpiredRetriesOnce(t *testing.T) {
+	var calls int32
+	c, ts := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		fmt.Fprint(w, `{"errcode":42001,"errmsg":"access_token expired"}`)
+	})
+	_, err := c.Media.GetReader("m")
+	var e *Error
+	if !errors.As(err, &e) || e.ErrCode != 42001 {
+		t.Fatalf("got err %v, want errcode 42001", err)
+	}
+	if calls != 2 || ts.tokenCalls != 2 {
+		t.Fatalf("got %d requests and %d token refreshes, want 2 and 2", calls, ts.tokenCalls)
+	}
+}
+
+func TestDoStreamStatus(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		header  map[string]string
+		body    string
+		wantErr bool
+	}{
+		{name: "file", status: http.StatusOK, body: "binary"},
+		{name: "partial", status: http.StatusPartialContent, body: "bin"},
+		{name: "json file", status: http.StatusOK, header: map[string]string{"Content-Disposition": `attachment; filename="a.json"`}, body: `{"errcode":1}`},
+		{name: "gateway error", status: http.StatusBadGateway, header: map[string]string{"Content-Type": "text/html"}, body: "<html>bad gateway</html>", wantErr: true},
+		{name: "not found", status: http.StatusNotFound, body: "not found", wantErr: true},
+		{name: "wecom error", status: http.StatusOK, body: `{"errcode":40007,"errmsg":"invalid media_id"}`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				for k, v := range tt.header {
+					w.Header().Set(k, v)
+				}
+				w.WriteHeader(tt.status)
+				fmt.Fprint(w, tt.body)
+			})
+			buf := &bytes.Buffer{}
+			file, err := c.Media.Get("m", buf)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("want error, got file %+v with body %q", file, buf.String())
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if buf.String() != tt.body || file.Written != int64(len(tt.body)) {
+				t.Fatalf("got body %q, written %d", buf.String(), file.Written)
+			}
+		})
+	}
+}
+
+func TestParseErrorBody(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		errcode int
+		isError bool
+	}{
+		{name: "binary", body: "\x89PNG"},
+		{name: "json without errcode", body: `{"a":1}`},
+		{name: "invalid json", body: `{"errcode":`},
+		{name: "large json", body: `{"errcode":1,"x":"` + strings.Repeat("x", maxErrorBodySize) + `"}`},
+		{name: "error", body: `{"errcode":40007,"errmsg":"invalid media_id"}`, errcode: 40007, isError: true},
+	}
+	for _, tt := range tests {
+		resp := &http.Response{Header: http.Header{}}
+		body := bufio.NewReader(strings.NewReader(tt.body))
+		result, data, err := parseErrorBody(resp, body)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if (result != nil) != tt.isError || result != nil && result.ErrCode != tt.errcode {
+			t.Fatalf("%s: got %+v", tt.name, result)
+		}
+		rest, _ := ioutil.ReadAll(body)
+		if got := string(data) + string(rest); got != tt.body {
+			t.Fatalf("%s: body not preserved", tt.name)
+		}
+	}
+}
+
+func TestGetTokenFailure(t *testing.T) {
+	var tokenCalls int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == pathGetToken {
+			atomic.AddInt32(&tokenCalls, 1)
+			// errmsg 同时包含 invalid 和 token，会被 tokenExpired 视为 token 过期
+			fmt.Fprint(w, `{"errcode":40001,"errmsg":"invalid credential, access_token is invalid"}`)
+			return
+		}
+		t.Errorf("unexpected request %s", r.URL.Path)
+	}))
+	defer ts.Close()
+	c, err := NewClient("corp", "bad", NewWithHostOption(ts.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	identities := make([]Identity, 8)
+	for i := range identities {
+		identities[i] = OpenIDIdentity(fmt.Sprint("o", i))
+	}
+	for _, r := range c.Address.ResolveUserIDs(context.Background(), identities, 4) {
+		var e *Error
+		if !errors.As(r.Err, &e) || e.ErrCode != 40001 {
+			t.Fatalf("got err %v, want errcode 40001", r.Err)
+		}
+	}
+	if _, err = c.Media.GetReader("m"); err == nil {
+		t.Fatal("want error from GetReader")
+	}
+	if n := atomic.LoadInt32(&tokenCalls); n != int32(len(identities))+1 {
+		t.Fatalf("gettoken called %d times, want %d", n, len(identities)+1)
+	}
+}